@@ -0,0 +1,177 @@
+package customerio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Activity is a single entry in the workspace activity log.
+type Activity struct {
+	ID           string                 `json:"id"`
+	CustomerID   string                 `json:"customer_id"`
+	DeliveryID   string                 `json:"delivery_id"`
+	DeliveryType string                 `json:"delivery_type"`
+	Type         string                 `json:"type"`
+	Name         string                 `json:"name"`
+	Timestamp    *time.Time             `json:"timestamp"`
+	Data         map[string]interface{} `json:"data"`
+}
+
+func (a *Activity) UnmarshalJSON(b []byte) error {
+	type activity Activity
+	var r struct {
+		activity
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	ts, err := parseCustomerIOTime(r.Timestamp)
+	if err != nil {
+		return err
+	}
+	*a = Activity(r.activity)
+	a.Timestamp = ts
+	return nil
+}
+
+// ActivityFilter narrows the activities returned by GetActivities.
+type ActivityFilter struct {
+	// Type limits results to activities of this type, e.g. "event".
+	Type string
+	// Name limits results to activities with this name, e.g. an event name.
+	Name string
+	// Start is the cursor returned by a previous call.
+	Start string
+	// Deleted, if set, limits results to activities for deleted (true) or
+	// existing (false) customers.
+	Deleted *bool
+	// Limit is the maximum number of activities to return; zero uses the API
+	// default.
+	Limit int
+	// Extra holds query parameters not modeled by the fields above. When a
+	// field above is set, it takes precedence over an Extra value with the
+	// same name.
+	Extra url.Values
+}
+
+func (f ActivityFilter) values() url.Values {
+	v := url.Values{}
+	for k, vs := range f.Extra {
+		v[k] = append([]string(nil), vs...)
+	}
+	if f.Type != "" {
+		v.Set("type", f.Type)
+	}
+	if f.Name != "" {
+		v.Set("name", f.Name)
+	}
+	if f.Start != "" {
+		v.Set("start", f.Start)
+	}
+	if f.Deleted != nil {
+		v.Set("deleted", strconv.FormatBool(*f.Deleted))
+	}
+	if f.Limit > 0 {
+		v.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return v
+}
+
+// GetActivities returns a page of activities matching filter, starting at
+// filter.Start, or at the first page if it is empty.
+func (c *APIClient) GetActivities(ctx context.Context, filter ActivityFilter) (PageResult[Activity], error) {
+	url := "/v1/activities"
+	if qs := filter.values().Encode(); qs != "" {
+		url += "?" + qs
+	}
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return PageResult[Activity]{}, err
+	}
+	if statusCode != http.StatusOK {
+		return PageResult[Activity]{}, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var resp struct {
+		Activities []Activity `json:"activities"`
+		Next       string     `json:"next"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return PageResult[Activity]{}, err
+	}
+	return PageResult[Activity]{Items: resp.Activities, NextCursor: resp.Next}, nil
+}
+
+// AttributeChange is a single change to a customer attribute.
+type AttributeChange struct {
+	Attribute string
+	// From is the value before the change, or nil if the attribute was
+	// unset.
+	From interface{}
+	// To is the value after the change, or nil if the attribute was removed.
+	To        interface{}
+	Timestamp *time.Time
+}
+
+// maxActivitiesPageSize is the largest page size accepted by the activities
+// endpoint.
+const maxActivitiesPageSize = 100
+
+// attributeChangeActivity is the activity type recorded when a customer
+// attribute changes.
+const attributeChangeActivity = "attribute_change"
+
+// GetAttributeHistory returns the recorded changes to a customer's attribute,
+// in the order the activity log returns them, newest first. Changes older
+// than the workspace's activity retention period are not available.
+func (c *APIClient) GetAttributeHistory(ctx context.Context, id string, idType IdentifierType, attribute string) ([]AttributeChange, error) {
+	if id == "" {
+		return nil, ParamError{Param: "id"}
+	}
+	if attribute == "" {
+		return nil, ParamError{Param: "attribute"}
+	}
+
+	var changes []AttributeChange
+	filter := ActivityFilter{
+		Type:  attributeChangeActivity,
+		Name:  attribute,
+		Limit: maxActivitiesPageSize,
+		Extra: url.Values{"customer_id": {id}, "id_type": {string(idType)}},
+	}
+	for {
+		page, err := c.GetActivities(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range page.Items {
+			if change, ok := attributeChange(a, attribute); ok {
+				changes = append(changes, change)
+			}
+		}
+		if page.NextCursor == "" || len(page.Items) == 0 {
+			return changes, nil
+		}
+		filter.Start = page.NextCursor
+	}
+}
+
+// attributeChange extracts the change to attribute described by a, if it is
+// an attribute change activity for that attribute. The activity's name is
+// the attribute, and its data holds the values before and after the change.
+func attributeChange(a Activity, attribute string) (AttributeChange, bool) {
+	if a.Type != attributeChangeActivity || a.Name != attribute {
+		return AttributeChange{}, false
+	}
+	return AttributeChange{
+		Attribute: attribute,
+		From:      a.Data["old_value"],
+		To:        a.Data["new_value"],
+		Timestamp: a.Timestamp,
+	}, true
+}
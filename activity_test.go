@@ -0,0 +1,119 @@
+package customerio_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestGetActivities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if got := q.Get("type"); got != "event" {
+			t.Errorf("typed field should take precedence. got: %s, want: event", got)
+		}
+		if got := q.Get("customer_id"); got != "c 1" {
+			t.Errorf("wrong extra parameter. got: %s, want: c 1", got)
+		}
+		if got := q.Get("limit"); got != "5" {
+			t.Errorf("wrong limit. got: %s, want: 5", got)
+		}
+		w.Write([]byte(`{"activities":[{"id":"a1","type":"event","name":"signup","timestamp":1500111111}],"next":"n1"}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	page, err := api.GetActivities(context.Background(), customerio.ActivityFilter{
+		Type:  "event",
+		Limit: 5,
+		Extra: url.Values{"type": {"page"}, "customer_id": {"c 1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NextCursor != "n1" {
+		t.Errorf("wrong cursor. got: %s, want: n1", page.NextCursor)
+	}
+	activities := page.Items
+	if len(activities) != 1 || activities[0].Name != "signup" {
+		t.Fatalf("wrong activities: %#v", activities)
+	}
+	if ts := activities[0].Timestamp; ts == nil || !ts.Equal(time.Unix(1500111111, 0)) {
+		t.Errorf("wrong timestamp: %v", ts)
+	}
+}
+
+func TestGetActivitiesDeletedFilter(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.Query()
+		w.Write([]byte(`{"activities":[]}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	if _, err := api.GetActivities(context.Background(), customerio.ActivityFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := query["deleted"]; ok {
+		t.Errorf("deleted should be omitted when unset, got: %v", query)
+	}
+
+	for _, deleted := range []bool{true, false} {
+		deleted := deleted
+		if _, err := api.GetActivities(context.Background(), customerio.ActivityFilter{Deleted: &deleted}); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := query.Get("deleted"), strconv.FormatBool(deleted); got != want {
+			t.Errorf("wrong deleted parameter. got: %q, want: %q", got, want)
+		}
+	}
+}
+
+func TestGetAttributeHistory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if q.Get("customer_id") != "1" || q.Get("id_type") != "id" || q.Get("type") != "attribute_change" {
+			t.Errorf("wrong query: %v", q)
+		}
+		if got := q.Get("limit"); got != "100" {
+			t.Errorf("wrong limit. got: %s, want: 100", got)
+		}
+		if q.Get("start") == "" {
+			w.Write([]byte(`{"activities":[` +
+				`{"type":"attribute_change","name":"plan","timestamp":1600000100,"data":{"old_value":"basic","new_value":"pro"}},` +
+				`{"type":"attribute_change","name":"email","timestamp":1600000050,"data":{"old_value":"a@example.com","new_value":"b@example.com"}}` +
+				`],"next":"n1"}`))
+			return
+		}
+		w.Write([]byte(`{"activities":[{"type":"attribute_change","name":"plan","timestamp":1600000000,"data":{"new_value":"basic"}}],"next":""}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	changes, err := api.GetAttributeHistory(context.Background(), "1", customerio.IdentifierTypeID, "plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("wrong number of changes. got: %d, want: 2", len(changes))
+	}
+	if changes[0].From != "basic" || changes[0].To != "pro" || changes[0].Timestamp.Unix() != 1600000100 {
+		t.Errorf("wrong first change: %+v", changes[0])
+	}
+	if changes[1].From != nil || changes[1].To != "basic" {
+		t.Errorf("wrong second change: %+v", changes[1])
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"io"
-	"io/ioutil"
 	"net/http"
 )
 
@@ -14,56 +13,80 @@ type APIClient struct {
 	URL       string
 	UserAgent string
 	Client    *http.Client
+
+	// Track is the track client used by the methods that write data the App
+	// API can only read, such as SyncSegmentMembership. It may be set with
+	// WithTrackClient.
+	Track *CustomerIO
+
+	transportConfig
+
+	objectTypes *objectTypeCache
 }
 
 // NewAPIClient prepares a client for use with the Customer.io API, see: https://customer.io/docs/api/#apicoreintroduction
 // using an App API Key from https://fly.customer.io/settings/api_credentials?keyType=app
 func NewAPIClient(key string, opts ...option) *APIClient {
 	client := &APIClient{
-		Key:       key,
-		Client:    http.DefaultClient,
-		URL:       "https://api.customer.io",
-		UserAgent: DefaultUserAgent,
+		Key:             key,
+		Client:          http.DefaultClient,
+		URL:             "https://api.customer.io",
+		UserAgent:       DefaultUserAgent,
+		transportConfig: newTransportConfig(),
 	}
 
 	for _, opt := range opts {
 		opt.api(client)
 	}
+	if client.pool != nil && client.Client == http.DefaultClient {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		client.pool.configure(transport)
+		client.Client = &http.Client{Transport: transport}
+	}
 	return client
 }
 
+// trackClient returns the track client set with WithTrackClient, or a
+// ParamError if there is none.
+func (c *APIClient) trackClient() (*CustomerIO, error) {
+	if c.Track == nil {
+		return nil, ParamError{Param: "track", Reason: "no track client set; see WithTrackClient"}
+	}
+	return c.Track, nil
+}
+
+// Close cancels any in-flight requests and closes idle connections. The
+// client can't be used after Close; later requests return ErrClientClosed.
+func (c *APIClient) Close() {
+	c.closeClient(c.Client)
+}
+
 func (c *APIClient) doRequest(ctx context.Context, verb, requestPath string, body interface{}) ([]byte, int, error) {
-	var payload io.Reader
+	var payload []byte
 
 	if body != nil {
 		b, err := json.Marshal(body)
 		if err != nil {
 			return nil, 0, err
 		}
-		payload = bytes.NewBuffer(b)
+		payload = b
 	}
 
-	req, err := http.NewRequest(verb, c.URL+requestPath, payload)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	req = req.WithContext(ctx)
-
-	req.Header.Set("Authorization", "Bearer "+c.Key)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Add("User-Agent", c.UserAgent)
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer resp.Body.Close()
+	return c.send(ctx, c.Client, appEndpointClass(requestPath), verb, requestPath, len(payload), func(ctx context.Context) (*http.Request, error) {
+		var r io.Reader
+		if payload != nil {
+			r = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequest(verb, c.URL+requestPath, r)
+		if err != nil {
+			return nil, err
+		}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, 0, err
-	}
+		req = req.WithContext(ctx)
 
-	return respBody, resp.StatusCode, nil
+		req.Header.Set("Authorization", "Bearer "+c.Key)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Add("User-Agent", c.UserAgent)
+		return req, nil
+	})
 }
@@ -0,0 +1,211 @@
+package customerio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTrackerClosed is returned when events are enqueued on a closed
+// AsyncTracker.
+var ErrTrackerClosed = errors.New("async tracker closed")
+
+// AsyncTrackerOptions configures an AsyncTracker.
+type AsyncTrackerOptions struct {
+	// BatchSize is the number of queued events that triggers a flush.
+	// Defaults to 100.
+	BatchSize int
+	// FlushInterval is the longest an event waits in the queue before being
+	// sent. Defaults to 5 seconds.
+	FlushInterval time.Duration
+	// FlushTimeout bounds each background flush. Defaults to 30 seconds.
+	FlushTimeout time.Duration
+	// OnError, if set, is called with errors from background flushes. If it
+	// isn't set, the last such error is kept and returned by Close instead;
+	// Stats counts every failed event either way.
+	OnError func(error)
+}
+
+// AsyncTrackerStats are counters describing an AsyncTracker's work so far.
+type AsyncTrackerStats struct {
+	Queued int
+	Sent   int
+	Failed int
+}
+
+// AsyncTracker buffers events and sends them in batches from a background
+// goroutine with TrackEventsBatch. It is safe for concurrent use.
+type AsyncTracker struct {
+	client *CustomerIO
+	opts   AsyncTrackerOptions
+
+	// mu guards queue, closed, stats and lastErr.
+	mu      sync.Mutex
+	queue   []BatchEvent
+	closed  bool
+	stats   AsyncTrackerStats
+	lastErr error
+
+	// flushMu serializes sending, so that batches are sent in order.
+	flushMu sync.Mutex
+
+	wake chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAsyncTracker starts an AsyncTracker sending events with c. Close must be
+// called to send remaining events and stop the background goroutine.
+func NewAsyncTracker(c *CustomerIO, opts AsyncTrackerOptions) *AsyncTracker {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = 30 * time.Second
+	}
+	t := &AsyncTracker{
+		client: c,
+		opts:   opts,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	t.wg.Add(1)
+	go t.run()
+	return t
+}
+
+// Enqueue adds an event to the queue. It returns ErrTrackerClosed once Close
+// has been called.
+func (t *AsyncTracker) Enqueue(e BatchEvent) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrTrackerClosed
+	}
+	t.queue = append(t.queue, e)
+	t.stats.Queued++
+	full := len(t.queue) >= t.opts.BatchSize
+	t.mu.Unlock()
+
+	if full {
+		t.Flush()
+	}
+	return nil
+}
+
+// Flush asks the background goroutine to send queued events without waiting
+// for them to be sent.
+func (t *AsyncTracker) Flush() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// FlushNow sends all queued events before returning, returning any error
+// from sending them.
+func (t *AsyncTracker) FlushNow(ctx context.Context) error {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	t.mu.Lock()
+	events := t.queue
+	t.queue = nil
+	t.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	err := t.client.TrackEventsBatch(ctx, events)
+	failed := 0
+	if err != nil {
+		var be *BatchError
+		if errors.As(err, &be) {
+			failed = len(be.Failures)
+		} else {
+			failed = len(events)
+		}
+	}
+
+	t.mu.Lock()
+	t.stats.Sent += len(events) - failed
+	t.stats.Failed += failed
+	t.mu.Unlock()
+	return err
+}
+
+// Stats returns the tracker's counters.
+func (t *AsyncTracker) Stats() AsyncTrackerStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// Close stops accepting events, sends any that are queued, and stops the
+// background goroutine. It returns ctx's error if ctx is done before a
+// background flush in progress finishes, and otherwise the error from sending
+// the remaining events joined with the last background flush error when
+// OnError isn't set. It is safe to call more than once.
+func (t *AsyncTracker) Close(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.done)
+	}
+	t.mu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	err := t.FlushNow(ctx)
+	t.mu.Lock()
+	lastErr := t.lastErr
+	t.lastErr = nil
+	t.mu.Unlock()
+	return errors.Join(lastErr, err)
+}
+
+func (t *AsyncTracker) run() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		case <-t.wake:
+		}
+		if err := t.flush(); err != nil {
+			if t.opts.OnError != nil {
+				t.opts.OnError(err)
+			} else {
+				t.mu.Lock()
+				t.lastErr = err
+				t.mu.Unlock()
+			}
+		}
+	}
+}
+
+// flush is a background flush, bounded by FlushTimeout.
+func (t *AsyncTracker) flush() error {
+	ctx, cancel := context.WithTimeout(context.Background(), t.opts.FlushTimeout)
+	defer cancel()
+	return t.FlushNow(ctx)
+}
@@ -0,0 +1,170 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestAsyncTrackerConcurrency(t *testing.T) {
+	var received int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var body struct {
+			Batch []map[string]interface{} `json:"batch"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Error(err)
+		}
+		atomic.AddInt64(&received, int64(len(body.Batch)))
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	tracker := customerio.NewAsyncTracker(client, customerio.AsyncTrackerOptions{
+		BatchSize:     10,
+		FlushInterval: time.Millisecond,
+	})
+
+	const (
+		workers = 20
+		events  = 50
+	)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < events; i++ {
+				err := tracker.Enqueue(customerio.BatchEvent{
+					CustomerID: strconv.Itoa(w),
+					Name:       "test",
+				})
+				if err != nil {
+					t.Error(err)
+				}
+				if i%10 == 0 {
+					tracker.Flush()
+				}
+			}
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			if err := tracker.FlushNow(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	var closers sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		closers.Add(1)
+		go func() {
+			defer closers.Done()
+			if err := tracker.Close(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	closers.Wait()
+
+	if err := tracker.Enqueue(customerio.BatchEvent{CustomerID: "1", Name: "late"}); err != customerio.ErrTrackerClosed {
+		t.Errorf("expected ErrTrackerClosed, got: %v", err)
+	}
+
+	stats := tracker.Stats()
+	want := customerio.AsyncTrackerStats{Queued: workers * events, Sent: workers * events}
+	if stats != want {
+		t.Errorf("wrong stats. got: %#v, want: %#v", stats, want)
+	}
+	if got := atomic.LoadInt64(&received); got != workers*events {
+		t.Errorf("wrong number of events received. got: %d, want: %d", got, workers*events)
+	}
+}
+
+func TestAsyncTrackerCloseDeadline(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	tracker := customerio.NewAsyncTracker(client, customerio.AsyncTrackerOptions{
+		FlushInterval: time.Hour,
+		FlushTimeout:  time.Hour,
+	})
+	if err := tracker.Enqueue(customerio.BatchEvent{CustomerID: "1", Name: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	tracker.Flush()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline to be exceeded, got: %v", err)
+	}
+}
+
+func TestAsyncTrackerFlushTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	tracker := customerio.NewAsyncTracker(client, customerio.AsyncTrackerOptions{
+		FlushInterval: time.Hour,
+		FlushTimeout:  20 * time.Millisecond,
+	})
+	if err := tracker.Enqueue(customerio.BatchEvent{CustomerID: "1", Name: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	tracker.Flush()
+	<-started
+
+	// Without OnError, the background flush's error is returned by Close.
+	if err := tracker.Close(context.Background()); err == nil {
+		t.Error("expected the timed out flush's error")
+	}
+	if stats := tracker.Stats(); stats.Failed != 1 {
+		t.Errorf("wrong failed count. got: %d, want: 1", stats.Failed)
+	}
+}
@@ -0,0 +1,436 @@
+package customerio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBatchRequestSize is the largest request body accepted by the v2
+	// batch endpoint.
+	maxBatchRequestSize = 500 * 1024
+	// maxBatchActionSize is the largest single action accepted by the v2
+	// batch endpoint.
+	maxBatchActionSize = 32 * 1024
+)
+
+// BatchPayloadSize returns the size in bytes of the request body that
+// TrackWriteBatch would send for actions.
+func BatchPayloadSize(actions []map[string]any) (int, error) {
+	b, err := json.Marshal(map[string]any{"batch": actions})
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// BatchFits reports whether actions can be sent with a single call to
+// TrackWriteBatch without exceeding the batch endpoint's limits on the size
+// of the request and of each action. Actions that can't be marshaled never
+// fit.
+func BatchFits(actions []map[string]any) bool {
+	size, err := BatchPayloadSize(actions)
+	if err != nil || size > maxBatchRequestSize {
+		return false
+	}
+	for _, action := range actions {
+		b, err := json.Marshal(action)
+		if err != nil || len(b) > maxBatchActionSize {
+			return false
+		}
+	}
+	return true
+}
+
+// BatchEvent is a single event to send with TrackEventsBatch.
+type BatchEvent struct {
+	CustomerID string
+	Name       string
+	// Timestamp is when the event happened. The zero value uses the time the
+	// event is received.
+	Timestamp time.Time
+	// Type is the kind of event: "event", "page" or "screen". Empty means
+	// "event".
+	Type string
+	Data map[string]interface{}
+}
+
+func (e BatchEvent) action(autoMillis bool) (map[string]any, error) {
+	if e.CustomerID == "" {
+		return nil, ParamError{Param: "customerID"}
+	}
+	if e.Name == "" {
+		return nil, ParamError{Param: "eventName"}
+	}
+	typ := e.Type
+	if typ == "" {
+		typ = "event"
+	}
+	action := map[string]any{
+		"type":        "person",
+		"action":      typ,
+		"identifiers": map[string]string{"id": e.CustomerID},
+		"name":        e.Name,
+	}
+	if !e.Timestamp.IsZero() {
+		ts, err := epochSeconds("timestamp", e.Timestamp, autoMillis)
+		if err != nil {
+			return nil, err
+		}
+		action["timestamp"] = ts
+	}
+	if e.Data != nil {
+		action["attributes"] = e.Data
+	}
+	return action, nil
+}
+
+// BatchError is returned by batch methods when some of the submitted items
+// could not be sent. Failures is keyed by the index of the item in the
+// caller's slice; items not present were sent successfully.
+type BatchError struct {
+	Failures map[int]error
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]int, 0, len(e.Failures))
+	for i := range e.Failures {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	msgs := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		msgs = append(msgs, fmt.Sprintf("%d: %v", i, e.Failures[i]))
+	}
+	return fmt.Sprintf("%d batch items failed: %s", len(indexes), strings.Join(msgs, "; "))
+}
+
+// batchActions lists the actions the v2 batch API accepts for each type.
+var batchActions = map[string][]string{
+	"person": {
+		"identify", "delete", "event", "page", "screen", "merge",
+		"add_relationships", "delete_relationships",
+		"add_device", "delete_device", "suppress", "unsuppress",
+	},
+	"object":   {"identify", "delete", "add_relationships", "delete_relationships"},
+	"delivery": {"event"},
+}
+
+// validateBatchAction checks that action has a recognized type and action,
+// and identifies who or what it applies to.
+func validateBatchAction(action map[string]any) error {
+	typ, _ := action["type"].(string)
+	actions, ok := batchActions[typ]
+	if !ok {
+		return ParamError{Param: "type", Reason: fmt.Sprintf("unrecognized type %q", typ)}
+	}
+	name, _ := action["action"].(string)
+	if !slices.Contains(actions, name) {
+		return ParamError{Param: "action", Reason: fmt.Sprintf("unrecognized %s action %q", typ, name)}
+	}
+
+	switch {
+	case typ == "person" && name == "merge":
+		for _, key := range []string{"primary", "secondary"} {
+			if !hasIdentifiers(action[key]) {
+				return ParamError{Param: key}
+			}
+		}
+	case typ == "person" && action["anonymous_id"] != nil && name != "identify":
+		// Anonymous events are identified by anonymous_id instead.
+	case !hasIdentifiers(action["identifiers"]):
+		return ParamError{Param: "identifiers"}
+	}
+	return nil
+}
+
+// validateBatchActions checks every action with validateBatchAction,
+// returning a *BatchError keyed by the index of each malformed action.
+func validateBatchActions(actions []map[string]any) error {
+	failures := map[int]error{}
+	for i, action := range actions {
+		if err := validateBatchAction(action); err != nil {
+			failures[i] = err
+		}
+	}
+	if len(failures) > 0 {
+		return &BatchError{Failures: failures}
+	}
+	return nil
+}
+
+func hasIdentifiers(v any) bool {
+	switch ids := v.(type) {
+	case map[string]string:
+		return len(ids) > 0
+	case map[string]any:
+		return len(ids) > 0
+	}
+	return false
+}
+
+// TrackEventsBatch sends events for any number of customers using the v2
+// batch API, splitting them into as many requests as needed to stay within
+// the endpoint's size limits. If any events are invalid or a request fails,
+// the remaining events are still sent and a *BatchError describes the events
+// that failed.
+func (c *CustomerIO) TrackEventsBatch(ctx context.Context, events []BatchEvent) error {
+	actions := make([]map[string]any, len(events))
+	failures := map[int]error{}
+	for i, e := range events {
+		action, err := e.action(c.autoMillis)
+		if err != nil {
+			failures[i] = err
+			continue
+		}
+		actions[i] = action
+	}
+	return c.writeBatches(ctx, actions, failures)
+}
+
+// DeleteAttributeForCustomers removes the attribute key from each of the
+// customers, identified by id, using the v2 batch API. An attribute is
+// removed by setting it to an empty value. The customers are split into as
+// many requests as needed, and if any requests fail the remaining customers
+// are still updated and a *BatchError describes the customers that failed.
+func (c *CustomerIO) DeleteAttributeForCustomers(ctx context.Context, customerIDs []string, key string) error {
+	if strings.TrimSpace(key) == "" {
+		return ParamError{Param: "key"}
+	}
+	if len(customerIDs) == 0 {
+		return ParamError{Param: "customerIDs"}
+	}
+
+	actions := make([]map[string]any, len(customerIDs))
+	failures := map[int]error{}
+	for i, id := range customerIDs {
+		if id == "" {
+			failures[i] = ParamError{Param: "customerID"}
+			continue
+		}
+		actions[i] = map[string]any{
+			"type":        "person",
+			"action":      "identify",
+			"identifiers": map[string]string{"id": id},
+			"attributes":  map[string]any{key: ""},
+		}
+	}
+	return c.writeBatches(ctx, actions, failures)
+}
+
+// writeBatches sends actions with TrackWriteBatch, split into requests within
+// the batch endpoint's size limits. Nil actions are skipped, so callers can
+// record invalid items in failures beforehand. Failures are keyed by the
+// index of the action, and returned as a *BatchError if there are any.
+func (c *CustomerIO) writeBatches(ctx context.Context, actions []map[string]any, failures map[int]error) error {
+	var (
+		batch   []map[string]any
+		indexes []int
+		size    int
+	)
+	if c.validateBatches {
+		// Validate up front so failures are keyed by the caller's index
+		// rather than the index within a split request.
+		for i, action := range actions {
+			if action == nil {
+				continue
+			}
+			if err := validateBatchAction(action); err != nil {
+				failures[i] = err
+				actions[i] = nil
+			}
+		}
+	}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := c.TrackWriteBatch(ctx, batch); err != nil {
+			for _, i := range indexes {
+				failures[i] = err
+			}
+		}
+		batch, indexes, size = nil, nil, 0
+	}
+
+	for i, action := range actions {
+		if action == nil {
+			continue
+		}
+		b, err := json.Marshal(action)
+		if err != nil {
+			failures[i] = err
+			continue
+		}
+		if len(b) > maxBatchActionSize {
+			failures[i] = fmt.Errorf("action is %d bytes, larger than the %d byte limit", len(b), maxBatchActionSize)
+			continue
+		}
+		// Leave room for the envelope and separating commas.
+		if size+len(b)+1 > maxBatchRequestSize-len(`{"batch":[]}`) {
+			flush()
+		}
+		batch = append(batch, action)
+		indexes = append(indexes, i)
+		size += len(b) + 1
+	}
+	flush()
+
+	if len(failures) > 0 {
+		return &BatchError{Failures: failures}
+	}
+	return nil
+}
+
+// ActionResult is the outcome of a single action sent with
+// TrackWriteBatchDetailed.
+type ActionResult struct {
+	// Index is the position of the action in the submitted slice.
+	Index int
+	OK    bool
+	// Error is the reason the action failed, if it did.
+	Error string
+}
+
+// TrackWriteBatchDetailed is like TrackWriteBatch but returns a result for
+// each action, aligned with actions, by parsing the per-action errors in the
+// batch response. An error is only returned if the response doesn't describe
+// individual actions, for example because the request failed entirely. As
+// with TrackWriteBatch, a client created with WithBatchValidation doesn't
+// send a batch with malformed actions, and returns a *BatchError instead.
+func (c *CustomerIO) TrackWriteBatchDetailed(ctx context.Context, actions []map[string]any) ([]ActionResult, error) {
+	if c.validateBatches {
+		if err := validateBatchActions(actions); err != nil {
+			return nil, err
+		}
+	}
+	body, err := c.request(ctx, "POST", fmt.Sprintf("%s/api/v2/batch", c.URL), map[string]any{
+		"batch": actions,
+	})
+	if err != nil {
+		var cerr *CustomerIOError
+		if !errors.As(err, &cerr) {
+			return nil, err
+		}
+		body = cerr.body
+	}
+
+	var resp struct {
+		Errors []struct {
+			BatchIndex *int   `json:"batch_index"`
+			Reason     string `json:"reason"`
+			Field      string `json:"field"`
+			Message    string `json:"message"`
+		} `json:"errors"`
+	}
+	if len(body) > 0 {
+		if jerr := json.Unmarshal(body, &resp); jerr != nil && err == nil {
+			return nil, jerr
+		}
+	}
+
+	results := make([]ActionResult, len(actions))
+	for i := range results {
+		results[i] = ActionResult{Index: i, OK: true}
+	}
+	described := false
+	for _, e := range resp.Errors {
+		if e.BatchIndex == nil || *e.BatchIndex < 0 || *e.BatchIndex >= len(actions) {
+			continue
+		}
+		msg := FieldError{Field: e.Field, Reason: e.Reason}
+		if e.Message != "" {
+			msg.Reason = e.Message
+		}
+		results[*e.BatchIndex].OK = false
+		results[*e.BatchIndex].Error = msg.String()
+		described = true
+	}
+	if err != nil && !described {
+		return nil, err
+	}
+	return results, nil
+}
+
+// BatchBuilder accumulates actions for TrackWriteBatch, producing the action
+// shapes expected by the v2 batch API. The zero value is ready to use.
+type BatchBuilder struct {
+	actions []map[string]any
+}
+
+func objectIdentifiers(typeID, objectID string) map[string]string {
+	return map[string]string{
+		"object_type_id": typeID,
+		"object_id":      objectID,
+	}
+}
+
+// IdentifyObject creates or updates an object of the given type.
+func (b *BatchBuilder) IdentifyObject(typeID, objectID string, attrs map[string]interface{}) {
+	action := map[string]any{
+		"type":        "object",
+		"action":      "identify",
+		"identifiers": objectIdentifiers(typeID, objectID),
+	}
+	if attrs != nil {
+		action["attributes"] = attrs
+	}
+	b.actions = append(b.actions, action)
+}
+
+// AddObjectRelationship relates a customer, identified by id, to an object.
+func (b *BatchBuilder) AddObjectRelationship(typeID, objectID, customerID string) {
+	b.actions = append(b.actions, map[string]any{
+		"type":        "object",
+		"action":      "add_relationships",
+		"identifiers": objectIdentifiers(typeID, objectID),
+		"cio_relationships": []map[string]any{
+			{"identifiers": map[string]string{"id": customerID}},
+		},
+	})
+}
+
+// DeleteObjectRelationship removes the relationship between a customer,
+// identified by id, and an object.
+func (b *BatchBuilder) DeleteObjectRelationship(typeID, objectID, customerID string) {
+	b.deleteObjectRelationship(typeID, objectID, map[string]string{"id": customerID})
+}
+
+// deleteObjectRelationship removes the relationship between the customer
+// with the given identifiers and an object.
+func (b *BatchBuilder) deleteObjectRelationship(typeID, objectID string, customer map[string]string) {
+	b.actions = append(b.actions, map[string]any{
+		"type":        "object",
+		"action":      "delete_relationships",
+		"identifiers": objectIdentifiers(typeID, objectID),
+		"cio_relationships": []map[string]any{
+			{"identifiers": customer},
+		},
+	})
+}
+
+// DeleteObject deletes an object.
+func (b *BatchBuilder) DeleteObject(typeID, objectID string) {
+	b.actions = append(b.actions, map[string]any{
+		"type":        "object",
+		"action":      "delete",
+		"identifiers": objectIdentifiers(typeID, objectID),
+	})
+}
+
+// Len returns the number of actions added so far.
+func (b *BatchBuilder) Len() int {
+	return len(b.actions)
+}
+
+// Actions returns the actions added so far, for use with TrackWriteBatch.
+func (b *BatchBuilder) Actions() []map[string]any {
+	return b.actions
+}
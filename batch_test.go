@@ -0,0 +1,250 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestBatchBuilderObjects(t *testing.T) {
+	var b customerio.BatchBuilder
+	b.IdentifyObject("1", "acme", map[string]interface{}{"name": "Acme"})
+	b.AddObjectRelationship("1", "acme", "cust1")
+	b.DeleteObject("1", "old")
+
+	got, err := json.Marshal(b.Actions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[` +
+		`{"action":"identify","attributes":{"name":"Acme"},"identifiers":{"object_id":"acme","object_type_id":"1"},"type":"object"},` +
+		`{"action":"add_relationships","cio_relationships":[{"identifiers":{"id":"cust1"}}],"identifiers":{"object_id":"acme","object_type_id":"1"},"type":"object"},` +
+		`{"action":"delete","identifiers":{"object_id":"old","object_type_id":"1"},"type":"object"}` +
+		`]`
+	if string(got) != want {
+		t.Errorf("wrong actions.\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestTrackWriteBatchDetailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":[{"batch_index":1,"field":"identifiers","reason":"required"}]}`))
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	results, err := client.TrackWriteBatchDetailed(context.Background(), []map[string]any{
+		{"type": "person", "action": "identify", "identifiers": map[string]string{"id": "1"}},
+		{"type": "person", "action": "identify"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []customerio.ActionResult{
+		{Index: 0, OK: true},
+		{Index: 1, OK: false, Error: "identifiers: required"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("wrong results. got: %#v, want: %#v", results, want)
+	}
+}
+
+func TestBatchValidation(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey", customerio.WithBatchValidation())
+	track.URL = srv.URL
+
+	var b customerio.BatchBuilder
+	b.IdentifyObject("1", "acme", nil)
+	actions := append(b.Actions(),
+		map[string]any{"action": "identify", "identifiers": map[string]string{"id": "1"}},
+		map[string]any{"type": "person", "action": "identify"},
+		map[string]any{"type": "person", "action": "event", "anonymous_id": "anon", "name": "viewed"},
+	)
+
+	err := track.TrackWriteBatch(context.Background(), actions)
+	var be *customerio.BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("expected BatchError, got: %#v", err)
+	}
+	want := map[int]string{1: "type", 2: "identifiers"}
+	if len(be.Failures) != len(want) {
+		t.Errorf("wrong failures. got: %v, want indexes: %v", be.Failures, want)
+	}
+	for i, param := range want {
+		var pe customerio.ParamError
+		if !errors.As(be.Failures[i], &pe) || pe.Param != param {
+			t.Errorf("wrong failure for action %d. got: %v, want param: %s", i, be.Failures[i], param)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("invalid batch was sent")
+	}
+
+	results, err := track.TrackWriteBatchDetailed(context.Background(), actions)
+	if !errors.As(err, &be) || len(be.Failures) != len(want) {
+		t.Errorf("expected the same BatchError from TrackWriteBatchDetailed, got: %v", err)
+	}
+	if results != nil || requests != 0 {
+		t.Errorf("invalid batch was sent")
+	}
+
+	if err := track.TrackWriteBatch(context.Background(), append(b.Actions(), actions[3])); err != nil {
+		t.Error(err)
+	}
+	if _, err := track.TrackWriteBatchDetailed(context.Background(), append(b.Actions(), actions[3])); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBatchFits(t *testing.T) {
+	var b customerio.BatchBuilder
+	b.IdentifyObject("1", "acme", map[string]interface{}{"name": "Acme"})
+	size, err := customerio.BatchPayloadSize(b.Actions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(map[string]any{"batch": b.Actions()})
+	if size != len(want) {
+		t.Errorf("wrong size. got: %d, want: %d", size, len(want))
+	}
+	if !customerio.BatchFits(b.Actions()) {
+		t.Error("small batch should fit")
+	}
+
+	b.IdentifyObject("1", "big", map[string]interface{}{"blob": strings.Repeat("x", 40*1024)})
+	if customerio.BatchFits(b.Actions()) {
+		t.Error("batch with an oversized action should not fit")
+	}
+}
+
+func TestTrackEventsBatchSplitting(t *testing.T) {
+	var (
+		requests    int
+		failedNames []string
+		firstAction map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.ContentLength > 500*1024 {
+			t.Errorf("request is %d bytes, larger than the batch limit", req.ContentLength)
+		}
+		var body struct {
+			Batch []map[string]any `json:"batch"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if requests == 1 {
+			firstAction = body.Batch[0]
+			return
+		}
+		// Fail every request after the first, so the failures must be
+		// mapped back to the events they carried.
+		for _, a := range body.Batch {
+			failedNames = append(failedNames, a["name"].(string))
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+
+	data := map[string]interface{}{"blob": strings.Repeat("x", 20*1024)}
+	events := make([]customerio.BatchEvent, 30)
+	for i := range events {
+		events[i] = customerio.BatchEvent{CustomerID: "c" + strconv.Itoa(i), Name: strconv.Itoa(i), Data: data}
+	}
+	events[0].Timestamp = time.Unix(1600000000, 0)
+	events[0].Type = "page"
+	events[3].Name = ""
+	events[5].Data = map[string]interface{}{"blob": strings.Repeat("x", 40*1024)}
+
+	err := track.TrackEventsBatch(context.Background(), events)
+	var be *customerio.BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("expected BatchError, got: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("wrong number of requests. got: %d, want: 2", requests)
+	}
+
+	if firstAction["action"] != "page" || firstAction["timestamp"] != float64(1600000000) ||
+		!reflect.DeepEqual(firstAction["identifiers"], map[string]any{"id": "c0"}) {
+		t.Errorf("wrong first action: %v", firstAction)
+	}
+
+	checkParamError(t, be.Failures[3], "eventName")
+	if be.Failures[5] == nil || !strings.Contains(be.Failures[5].Error(), "byte limit") {
+		t.Errorf("expected oversized action error for event 5, got: %v", be.Failures[5])
+	}
+	if len(failedNames) == 0 {
+		t.Fatal("second request carried no events")
+	}
+	for _, name := range failedNames {
+		i, _ := strconv.Atoi(name)
+		if be.Failures[i] == nil {
+			t.Errorf("event %d was in a failed request but has no failure", i)
+		}
+	}
+	if want := len(failedNames) + 2; len(be.Failures) != want {
+		t.Errorf("wrong number of failures. got: %d, want: %d", len(be.Failures), want)
+	}
+}
+
+func TestDeleteAttributeForCustomers(t *testing.T) {
+	var actions []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/api/v2/batch" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		var body struct {
+			Batch []map[string]any `json:"batch"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		actions = append(actions, body.Batch...)
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+	ctx := context.Background()
+
+	checkParamError(t, track.DeleteAttributeForCustomers(ctx, []string{"1"}, " "), "key")
+	checkParamError(t, track.DeleteAttributeForCustomers(ctx, nil, "plan"), "customerIDs")
+
+	err := track.DeleteAttributeForCustomers(ctx, []string{"1", "", "3"}, "plan")
+	var be *customerio.BatchError
+	if !errors.As(err, &be) || len(be.Failures) != 1 {
+		t.Fatalf("expected a single failure, got: %v", err)
+	}
+	checkParamError(t, be.Failures[1], "customerID")
+
+	want := []map[string]any{
+		{"type": "person", "action": "identify", "identifiers": map[string]any{"id": "1"}, "attributes": map[string]any{"plan": ""}},
+		{"type": "person", "action": "identify", "identifiers": map[string]any{"id": "3"}, "attributes": map[string]any{"plan": ""}},
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("wrong actions. got: %v, want: %v", actions, want)
+	}
+}
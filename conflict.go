@@ -0,0 +1,104 @@
+package customerio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy decides what IdentifyWithConflictPolicy does when the email
+// being set already belongs to another profile.
+type ConflictPolicy int
+
+const (
+	// ConflictPolicyError returns an *EmailConflictError without identifying.
+	// This is the default.
+	ConflictPolicyError ConflictPolicy = iota
+	// ConflictPolicyMerge merges the profiles owning the email into the
+	// customer being identified, then identifies it.
+	ConflictPolicyMerge
+	// ConflictPolicyProceed identifies the customer anyway, leaving the
+	// outcome to Customer.io's workspace settings.
+	ConflictPolicyProceed
+)
+
+// EmailConflictError is returned by IdentifyWithConflictPolicy when the email
+// belongs to other profiles and the policy is ConflictPolicyError, and by
+// GetCustomerByIdentifier when the email belongs to several profiles.
+type EmailConflictError struct {
+	Email string
+	// CioIDs are the profiles that already have the email.
+	CioIDs []string
+}
+
+func (e *EmailConflictError) Error() string {
+	return fmt.Sprintf("email %s already belongs to %s", e.Email, strings.Join(e.CioIDs, ", "))
+}
+
+// IdentifyWithConflictPolicy identifies the customer with the given id,
+// setting their email alongside attrs. Before identifying, the App API client
+// set with WithAPIClient is used to check whether other profiles already
+// have the email, and if so policy decides whether to fail, merge those
+// profiles into this customer, or identify regardless. If the check fails,
+// nothing is identified or merged.
+func (c *CustomerIO) IdentifyWithConflictPolicy(ctx context.Context, customerID, email string, attrs map[string]interface{}, policy ConflictPolicy) error {
+	if customerID == "" {
+		return ParamError{Param: "customerID"}
+	}
+	if email == "" {
+		return ParamError{Param: "email"}
+	}
+	api, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+
+	owners, err := api.LookupCustomersByEmail(ctx, email)
+	if err != nil && !errors.Is(err, ErrCustomerNotFound) {
+		return err
+	}
+	var self string
+	if len(owners) > 0 {
+		customer, err := api.GetCustomer(ctx, customerID, IdentifierTypeID)
+		if err != nil && !errors.Is(err, ErrCustomerNotFound) {
+			return err
+		}
+		self = customer.CioID
+	}
+	var conflicts []string
+	for _, cioID := range owners {
+		if cioID != "" && cioID != self {
+			conflicts = append(conflicts, cioID)
+		}
+	}
+
+	if attrs == nil {
+		attrs = map[string]interface{}{}
+	}
+	withEmail := make(map[string]interface{}, len(attrs)+1)
+	for k, v := range attrs {
+		withEmail[k] = v
+	}
+	withEmail["email"] = email
+
+	if len(conflicts) == 0 || policy == ConflictPolicyProceed {
+		return c.IdentifyCtx(ctx, customerID, withEmail)
+	}
+	if policy != ConflictPolicyMerge {
+		return &EmailConflictError{Email: email, CioIDs: conflicts}
+	}
+
+	// Make sure the primary profile exists before merging into it.
+	if err := c.IdentifyCtx(ctx, customerID, attrs); err != nil {
+		return err
+	}
+	primary := Identifier{Type: IdentifierTypeID, Value: customerID}
+	for _, cioID := range conflicts {
+		secondary := Identifier{Type: IdentifierTypeCioID, Value: cioID}
+		if err := c.MergeCustomersCtx(ctx, primary, secondary); err != nil {
+			return err
+		}
+	}
+	return c.IdentifyCtx(ctx, customerID, withEmail)
+}
@@ -0,0 +1,111 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestIdentifyWithConflictPolicy(t *testing.T) {
+	var writes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "GET" && req.URL.Path == "/v1/customers":
+			switch req.URL.Query().Get("email") {
+			case "taken@example.com":
+				w.Write([]byte(`{"results":[{"cio_id":"self"},{"cio_id":"other"}]}`))
+			case "mine@example.com":
+				w.Write([]byte(`{"results":[{"cio_id":"self"}]}`))
+			case "free@example.com":
+				w.Write([]byte(`{"results":[]}`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		case req.Method == "GET" && req.URL.Path == "/v1/customers/1/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"1","cio_id":"self"}}}`))
+		case req.Method == "PUT" && req.URL.Path == "/api/v1/customers/1":
+			var attrs map[string]interface{}
+			if err := json.NewDecoder(req.Body).Decode(&attrs); err != nil {
+				t.Error(err)
+			}
+			email, _ := attrs["email"].(string)
+			writes = append(writes, "identify "+email)
+		case req.Method == "POST" && req.URL.Path == "/api/v1/merge_customers":
+			var body struct {
+				Primary   map[string]string `json:"primary"`
+				Secondary map[string]string `json:"secondary"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			writes = append(writes, "merge "+body.Secondary["cio_id"]+" into "+body.Primary["id"])
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	track := customerio.NewTrackClient("siteid", "apikey", customerio.WithAPIClient(api))
+	track.URL = srv.URL
+	ctx := context.Background()
+	attrs := map[string]interface{}{"plan": "pro"}
+
+	cases := []struct {
+		name   string
+		email  string
+		policy customerio.ConflictPolicy
+		err    error
+		writes []string
+	}{
+		{"error", "taken@example.com", customerio.ConflictPolicyError,
+			&customerio.EmailConflictError{Email: "taken@example.com", CioIDs: []string{"other"}}, nil},
+		{"proceed", "taken@example.com", customerio.ConflictPolicyProceed,
+			nil, []string{"identify taken@example.com"}},
+		{"merge", "taken@example.com", customerio.ConflictPolicyMerge,
+			nil, []string{"identify ", "merge other into 1", "identify taken@example.com"}},
+		{"own email", "mine@example.com", customerio.ConflictPolicyError,
+			nil, []string{"identify mine@example.com"}},
+		{"no owner", "free@example.com", customerio.ConflictPolicyMerge,
+			nil, []string{"identify free@example.com"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			writes = nil
+			err := track.IdentifyWithConflictPolicy(ctx, "1", c.email, attrs, c.policy)
+			if !reflect.DeepEqual(err, c.err) {
+				t.Errorf("wrong error. got: %v, want: %v", err, c.err)
+			}
+			if !reflect.DeepEqual(writes, c.writes) {
+				t.Errorf("wrong writes. got: %q, want: %q", writes, c.writes)
+			}
+		})
+	}
+
+	t.Run("lookup failure", func(t *testing.T) {
+		writes = nil
+		err := track.IdentifyWithConflictPolicy(ctx, "1", "broken@example.com", attrs, customerio.ConflictPolicyMerge)
+		var cioErr *customerio.CustomerIOError
+		if !errors.As(err, &cioErr) {
+			t.Errorf("expected the lookup's error, got: %v", err)
+		}
+		if len(writes) != 0 {
+			t.Errorf("writes were sent after the lookup failed: %q", writes)
+		}
+	})
+
+	if _, ok := attrs["email"]; ok {
+		t.Error("caller's attributes were modified")
+	}
+
+	err := customerio.NewTrackClient("siteid", "apikey").IdentifyWithConflictPolicy(ctx, "1", "free@example.com", attrs, customerio.ConflictPolicyMerge)
+	checkParamError(t, err, "api")
+}
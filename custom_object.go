@@ -3,8 +3,14 @@ package customerio
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 type CustomObject struct {
@@ -41,36 +47,143 @@ func (c *APIClient) ListCustomObjects(ctx context.Context) ([]CustomObject, erro
 	return respObj.Types, nil
 }
 
+// ErrObjectTypeNotFound is returned when no object type matches a lookup.
+var ErrObjectTypeNotFound = errors.New("object type not found")
+
+// GetObjectTypeBySlug returns the object type with the given slug. There is
+// no endpoint to look up a type by slug, so this lists the object types and
+// matches them against both the plural and singular slugs.
+func (c *APIClient) GetObjectTypeBySlug(ctx context.Context, slug string) (CustomObject, error) {
+	if slug == "" {
+		return CustomObject{}, ParamError{Param: "slug"}
+	}
+	types, err := c.objectTypes.list(ctx, c.ListCustomObjects)
+	if err != nil {
+		return CustomObject{}, err
+	}
+	return findObjectTypeBySlug(types, slug)
+}
+
+// RefreshObjectTypes reloads the object types cached for slug lookups. It
+// does nothing unless the client was created with WithObjectTypeCache.
+func (c *APIClient) RefreshObjectTypes(ctx context.Context) error {
+	return c.objectTypes.refresh(ctx, c.ListCustomObjects)
+}
+
+// objectTypeCache holds the object type list for WithObjectTypeCache. A nil
+// cache always lists the object types.
+type objectTypeCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	types   []CustomObject
+	fetched time.Time
+}
+
+func (oc *objectTypeCache) list(ctx context.Context, fetch func(context.Context) ([]CustomObject, error)) ([]CustomObject, error) {
+	if oc == nil {
+		return fetch(ctx)
+	}
+	oc.mu.Lock()
+	types, fetched := oc.types, oc.fetched
+	oc.mu.Unlock()
+	if !fetched.IsZero() && time.Since(fetched) < oc.ttl {
+		return types, nil
+	}
+	if err := oc.refresh(ctx, fetch); err != nil {
+		return nil, err
+	}
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	return oc.types, nil
+}
+
+func (oc *objectTypeCache) refresh(ctx context.Context, fetch func(context.Context) ([]CustomObject, error)) error {
+	if oc == nil {
+		return nil
+	}
+	types, err := fetch(ctx)
+	if err != nil {
+		return err
+	}
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.types = types
+	oc.fetched = time.Now()
+	return nil
+}
+
+func findObjectTypeBySlug(types []CustomObject, slug string) (CustomObject, error) {
+	for _, t := range types {
+		if t.Slug == slug || t.SingularSlug == slug {
+			return t, nil
+		}
+	}
+	return CustomObject{}, ErrObjectTypeNotFound
+}
+
+// DeleteObjectType would delete a custom object type, but the Customer.io
+// API doesn't document any way to delete or disable object types, so it
+// always returns ErrNotSupported without making a request. Object types are
+// managed in the Customer.io UI; the objects of a type can be deleted with
+// BatchBuilder.DeleteObject.
+func (c *APIClient) DeleteObjectType(ctx context.Context, objectTypeID string) error {
+	if objectTypeID == "" {
+		return ParamError{Param: "objectTypeID"}
+	}
+	return ErrNotSupported
+}
+
+// FindCustomObjects returns the ids of all objects of the given type matching
+// filter, following pagination until every match has been read.
 func (c *APIClient) FindCustomObjects(ctx context.Context, objectTypeID string, filter map[string]any) ([]string, error) {
-	body, statusCode, err := c.doRequest(ctx, "POST", "/v1/objects", map[string]any{
+	return c.FindCustomObjectsLimit(ctx, objectTypeID, filter, 0)
+}
+
+// FindCustomObjectsLimit is like FindCustomObjects but returns at most limit
+// ids. A limit of zero or less reads every page.
+func (c *APIClient) FindCustomObjectsLimit(ctx context.Context, objectTypeID string, filter map[string]any, limit int) ([]string, error) {
+	return collectPages(limit, func(cursor string, pageSize int) (PageResult[string], error) {
+		return c.FindCustomObjectsPage(ctx, objectTypeID, filter, cursor, pageSize)
+	})
+}
+
+// FindCustomObjectsPage returns a single page of the ids of objects of the
+// given type matching filter, starting at cursor, or at the first page if
+// cursor is empty. A limit of zero or less uses the API's default page size.
+func (c *APIClient) FindCustomObjectsPage(ctx context.Context, objectTypeID string, filter map[string]any, cursor string, limit int) (PageResult[string], error) {
+	url := "/v1/objects?" + pageValues(cursor, limit).Encode()
+	body, statusCode, err := c.doRequest(ctx, "POST", url, map[string]any{
 		"object_type_id": objectTypeID,
 		"filter":         filter,
 	})
 	if err != nil {
-		return nil, err
+		return PageResult[string]{}, err
 	}
 	if statusCode != http.StatusOK {
-		return nil, &CustomerIOError{status: statusCode, url: "/v1/object_types", body: body}
+		return PageResult[string]{}, &CustomerIOError{status: statusCode, url: url, body: body}
 	}
 
 	var respObj struct {
-		IDs []string `json:"ids"`
+		IDs  []string `json:"ids"`
+		Next string   `json:"next"`
 	}
 
 	if err := json.Unmarshal(body, &respObj); err != nil {
-		return nil, err
+		return PageResult[string]{}, err
 	}
 
-	return respObj.IDs, nil
+	return PageResult[string]{Items: respObj.IDs, NextCursor: respObj.Next}, nil
 }
 
 func (c *APIClient) GetCustomObjectAttributes(ctx context.Context, objectTypeID, objectID string) (map[string]any, error) {
-	body, statusCode, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/objects/%s/%s/attributes", objectTypeID, objectID), nil)
+	url := fmt.Sprintf("/v1/objects/%s/%s/attributes", objectTypeID, objectID)
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	if statusCode != http.StatusOK {
-		return nil, &CustomerIOError{status: statusCode, url: "/v1/object_types", body: body}
+		return nil, &CustomerIOError{status: statusCode, url: url, body: body}
 	}
 
 	var respObj struct {
@@ -85,7 +198,236 @@ func (c *APIClient) GetCustomObjectAttributes(ctx context.Context, objectTypeID,
 	return respObj.Object.Attributes, nil
 }
 
+// GetObjectRelationships returns every customer related to an object,
+// following pagination until it is exhausted. Customers only have their
+// identifiers populated.
+func (c *APIClient) GetObjectRelationships(ctx context.Context, objectTypeID, objectID string) ([]Customer, error) {
+	return collectPages(0, func(cursor string, pageSize int) (PageResult[Customer], error) {
+		return c.GetObjectRelationshipsPage(ctx, objectTypeID, objectID, cursor, pageSize)
+	})
+}
+
+// GetObjectRelationshipsPage returns a single page of the customers related to
+// an object, starting at cursor, or at the first page if cursor is empty. A
+// limit of zero or less uses the API's default page size.
+func (c *APIClient) GetObjectRelationshipsPage(ctx context.Context, objectTypeID, objectID, cursor string, limit int) (PageResult[Customer], error) {
+	if objectTypeID == "" {
+		return PageResult[Customer]{}, ParamError{Param: "objectTypeID"}
+	}
+	if objectID == "" {
+		return PageResult[Customer]{}, ParamError{Param: "objectID"}
+	}
+	url := fmt.Sprintf("/v1/objects/%s/%s/relationships?%s",
+		url.PathEscape(objectTypeID), url.PathEscape(objectID), pageValues(cursor, limit).Encode())
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return PageResult[Customer]{}, err
+	}
+	if statusCode != http.StatusOK {
+		return PageResult[Customer]{}, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var resp struct {
+		Relationships []struct {
+			Identifiers struct {
+				CioID jsonID `json:"cio_id"`
+				Email string `json:"email"`
+				ID    jsonID `json:"id"`
+			} `json:"identifiers"`
+		} `json:"cio_relationships"`
+		Next string `json:"next"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return PageResult[Customer]{}, err
+	}
+
+	customers := make([]Customer, len(resp.Relationships))
+	for i, r := range resp.Relationships {
+		customers[i] = Customer{CioID: string(r.Identifiers.CioID), Email: r.Identifiers.Email, ID: string(r.Identifiers.ID)}
+	}
+	return PageResult[Customer]{Items: customers, NextCursor: resp.Next}, nil
+}
+
+// SyncObjectRelationships makes the customers related to an object match
+// desiredCustomerIDs exactly. Current relationships are read with the App API
+// client set by WithAPIClient, and the additions and removals are written
+// with the batch API. Related customers without an id can't be among the
+// desired customers, so they are removed by cio_id. The counts of
+// relationships added and removed are returned; if some actions fail, the
+// counts only include those that succeeded and err is a *BatchError.
+func (c *CustomerIO) SyncObjectRelationships(ctx context.Context, objectTypeID, objectID string, desiredCustomerIDs []string) (added, removed int, err error) {
+	api, err := c.apiClient()
+	if err != nil {
+		return 0, 0, err
+	}
+	related, err := api.GetObjectRelationships(ctx, objectTypeID, objectID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	current := map[string]bool{}
+	for _, customer := range related {
+		if customer.ID != "" {
+			current[customer.ID] = true
+		}
+	}
+
+	var b BatchBuilder
+	want := map[string]bool{}
+	for _, id := range desiredCustomerIDs {
+		if id == "" || want[id] {
+			continue
+		}
+		want[id] = true
+		if !current[id] {
+			b.AddObjectRelationship(objectTypeID, objectID, id)
+		}
+	}
+	adds := b.Len()
+	for _, customer := range related {
+		switch {
+		case customer.ID != "":
+			if !want[customer.ID] {
+				b.DeleteObjectRelationship(objectTypeID, objectID, customer.ID)
+			}
+		case customer.CioID != "":
+			b.deleteObjectRelationship(objectTypeID, objectID, map[string]string{"cio_id": customer.CioID})
+		}
+	}
+	if b.Len() == 0 {
+		return 0, 0, nil
+	}
+
+	failures := map[int]error{}
+	err = c.writeBatches(ctx, b.Actions(), failures)
+	for i := 0; i < b.Len(); i++ {
+		if _, failed := failures[i]; failed {
+			continue
+		}
+		if i < adds {
+			added++
+		} else {
+			removed++
+		}
+	}
+	return added, removed, err
+}
+
+// ObjectTypeStat is an object type with the number of objects of that type.
+type ObjectTypeStat struct {
+	CustomObject
+	Count int
+}
+
+// allObjectsFilter matches every object of a type, since every object has
+// an object_id attribute. It's used instead of a filter without conditions,
+// which the search API doesn't document as matching everything.
+var allObjectsFilter = map[string]any{
+	"and": []any{map[string]any{
+		"object_attribute": map[string]any{"field": "object_id", "operator": "exists"},
+	}},
+}
+
+// ListObjectTypesWithCounts returns every object type along with the number
+// of objects of each type. There is no count endpoint, so each type's
+// objects are listed in full with a bounded number of types in flight; this
+// is slow for types with many objects.
+func (c *APIClient) ListObjectTypesWithCounts(ctx context.Context) ([]ObjectTypeStat, error) {
+	types, err := c.ListCustomObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]ObjectTypeStat, len(types))
+	errs := make([]error, len(types))
+	sem := make(chan struct{}, findObjectsConcurrency)
+	var wg sync.WaitGroup
+	for i, ot := range types {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ot CustomObject) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ids, err := c.FindCustomObjects(ctx, ot.ID, allObjectsFilter)
+			if err != nil {
+				errs[i] = fmt.Errorf("object type %s: %w", ot.ID, err)
+				return
+			}
+			stats[i] = ObjectTypeStat{CustomObject: ot, Count: len(ids)}
+		}(i, ot)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Object is a custom object instance with its attributes.
+type Object struct {
+	TypeID     string         `json:"object_type_id"`
+	ID         string         `json:"object_id"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// findObjectsConcurrency bounds the requests made concurrently by
+// FindCustomObjectsWithAttributes and ListObjectTypesWithCounts.
+const findObjectsConcurrency = 8
+
+// FindCustomObjectsWithAttributes is like FindCustomObjects but also fetches
+// the attributes of each matching object, with a bounded number of requests
+// in flight. If some attributes can't be fetched, the objects that were
+// fetched are returned, in match order, along with an error for each failure.
+func (c *APIClient) FindCustomObjectsWithAttributes(ctx context.Context, objectTypeID string, filter map[string]any) ([]Object, error) {
+	ids, err := c.FindCustomObjects(ctx, objectTypeID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]*Object, len(ids))
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, findObjectsConcurrency)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			attributes, err := c.GetCustomObjectAttributes(ctx, objectTypeID, id)
+			if err != nil {
+				errs[i] = fmt.Errorf("object %s: %w", id, err)
+				return
+			}
+			objects[i] = &Object{TypeID: objectTypeID, ID: id, Attributes: attributes}
+		}(i, id)
+	}
+	wg.Wait()
+
+	result := make([]Object, 0, len(ids))
+	for _, o := range objects {
+		if o != nil {
+			result = append(result, *o)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// TrackWriteBatch sends actions in a single request to the v2 batch API. If
+// the client was created with WithBatchValidation, the actions are checked
+// first and a *BatchError describes any that are malformed; nothing is sent
+// in that case.
 func (c *CustomerIO) TrackWriteBatch(ctx context.Context, actions []map[string]any) error {
+	if c.validateBatches {
+		if err := validateBatchActions(actions); err != nil {
+			return err
+		}
+	}
 	_, err := c.request(ctx, "POST", fmt.Sprintf("%s/api/v2/batch", c.URL), map[string]any{
 		"batch": actions,
 	})
@@ -95,3 +437,140 @@ func (c *CustomerIO) TrackWriteBatch(ctx context.Context, actions []map[string]a
 
 	return nil
 }
+
+// ObjectWriteMode decides how CreateOrUpdateObject treats an object's existing
+// attributes.
+type ObjectWriteMode int
+
+const (
+	// ObjectWriteMerge updates the attributes in the payload and leaves the
+	// object's other attributes unchanged. This is the default.
+	ObjectWriteMerge ObjectWriteMode = iota
+	// ObjectWriteReplace also removes the object's attributes that are not in
+	// the payload, so the object ends up with exactly the attributes sent.
+	ObjectWriteReplace
+)
+
+// CreateOrUpdateObject creates or updates an object with attrs. With
+// ObjectWriteReplace, the App API client set by WithAPIClient is used to read
+// the object's current attributes first, which costs an extra request, and
+// any not in attrs are removed by setting them to an empty value. Only
+// ObjectWriteReplace needs the App API client.
+func (c *CustomerIO) CreateOrUpdateObject(ctx context.Context, objectTypeID, objectID string, attrs map[string]any, mode ObjectWriteMode) error {
+	if objectTypeID == "" {
+		return ParamError{Param: "objectTypeID"}
+	}
+	if objectID == "" {
+		return ParamError{Param: "objectID"}
+	}
+
+	if mode == ObjectWriteReplace {
+		api, err := c.apiClient()
+		if err != nil {
+			return err
+		}
+		current, err := api.GetCustomObjectAttributes(ctx, objectTypeID, objectID)
+		var cerr *CustomerIOError
+		if errors.As(err, &cerr) && cerr.status == http.StatusNotFound {
+			current, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		replaced := make(map[string]any, len(attrs)+len(current))
+		for k := range current {
+			// Identifiers and attributes maintained by Customer.io can't be
+			// removed.
+			if k == "object_id" || k == "id" || strings.HasPrefix(k, "_") {
+				continue
+			}
+			replaced[k] = ""
+		}
+		for k, v := range attrs {
+			replaced[k] = v
+		}
+		attrs = replaced
+	}
+
+	var b BatchBuilder
+	b.IdentifyObject(objectTypeID, objectID, attrs)
+	return c.TrackWriteBatch(ctx, b.Actions())
+}
+
+// AttributeDefinition describes an attribute observed on objects of a custom
+// object type.
+type AttributeDefinition struct {
+	Name string `json:"name"`
+	// Type is the JSON type of the attribute's values: "string", "number",
+	// "boolean", "object", "array", or "mixed" if sampled objects disagree.
+	Type string `json:"type"`
+	// Required is true if the attribute was present on every sampled object.
+	Required bool `json:"required"`
+}
+
+// objectSchemaSampleSize is the number of objects inspected by
+// GetObjectTypeSchema.
+const objectSchemaSampleSize = 20
+
+// GetObjectTypeSchema returns the attribute definitions for a custom object
+// type. Customer.io does not expose object type schemas, so the definitions
+// are inferred from a sample of existing objects of the type: attributes that
+// no sampled object has are not reported, and Required only means the
+// attribute was present on every sampled object. A type with no objects
+// returns no definitions.
+func (c *APIClient) GetObjectTypeSchema(ctx context.Context, objectTypeID string) ([]AttributeDefinition, error) {
+	ids, err := c.FindCustomObjectsLimit(ctx, objectTypeID, allObjectsFilter, objectSchemaSampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		names []string
+		types = map[string]string{}
+		seen  = map[string]int{}
+	)
+	for _, id := range ids {
+		attributes, err := c.GetCustomObjectAttributes(ctx, objectTypeID, id)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range attributes {
+			t := attributeType(value)
+			if prev, ok := types[name]; !ok {
+				names = append(names, name)
+				types[name] = t
+			} else if prev != t {
+				types[name] = "mixed"
+			}
+			seen[name]++
+		}
+	}
+
+	sort.Strings(names)
+	definitions := make([]AttributeDefinition, len(names))
+	for i, name := range names {
+		definitions[i] = AttributeDefinition{
+			Name:     name,
+			Type:     types[name],
+			Required: seen[name] == len(ids),
+		}
+	}
+	return definitions, nil
+}
+
+func attributeType(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return "mixed"
+}
@@ -0,0 +1,371 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestObjectTypeCache(t *testing.T) {
+	lists := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lists++
+		w.Write([]byte(`{"types":[{"id":"1","slug":"companies","singular_slug":"company"}]}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey", customerio.WithObjectTypeCache(time.Hour))
+	api.URL = srv.URL
+
+	ctx := context.Background()
+	for _, slug := range []string{"companies", "company"} {
+		ot, err := api.GetObjectTypeBySlug(ctx, slug)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ot.ID != "1" {
+			t.Errorf("wrong object type for %s: %#v", slug, ot)
+		}
+	}
+	if _, err := api.GetObjectTypeBySlug(ctx, "people"); err != customerio.ErrObjectTypeNotFound {
+		t.Errorf("expected ErrObjectTypeNotFound, got: %v", err)
+	}
+	if lists != 1 {
+		t.Errorf("wrong number of list requests. got: %d, want: 1", lists)
+	}
+
+	if err := api.RefreshObjectTypes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if lists != 2 {
+		t.Errorf("refresh did not reload object types")
+	}
+}
+
+func TestSyncObjectRelationships(t *testing.T) {
+	var actions []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/objects/1/acme/relationships":
+			w.Write([]byte(`{"cio_relationships":[{"identifiers":{"id":"keep"}},{"identifiers":{"id":"stale"}},{"identifiers":{"cio_id":"anon","email":"anon@example.com"}}],"next":""}`))
+		case "/api/v2/batch":
+			var body struct {
+				Batch []map[string]any `json:"batch"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			actions = append(actions, body.Batch...)
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	track := customerio.NewTrackClient("siteid", "apikey", customerio.WithAPIClient(api))
+	track.URL = srv.URL
+
+	added, removed, err := track.SyncObjectRelationships(context.Background(), "1", "acme", []string{"keep", "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 || removed != 2 {
+		t.Errorf("wrong counts. got: added=%d removed=%d, want: added=1 removed=2", added, removed)
+	}
+
+	// Actions are keyed by the customer's identifier type and value.
+	got := map[string]string{}
+	for _, a := range actions {
+		rels := a["cio_relationships"].([]any)
+		for k, v := range rels[0].(map[string]any)["identifiers"].(map[string]any) {
+			got[k+"="+v.(string)] = a["action"].(string)
+		}
+	}
+	want := map[string]string{
+		"id=new":      "add_relationships",
+		"id=stale":    "delete_relationships",
+		"cio_id=anon": "delete_relationships",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong actions. got: %v, want: %v", got, want)
+	}
+
+	_, _, err = customerio.NewTrackClient("siteid", "apikey").SyncObjectRelationships(context.Background(), "1", "acme", nil)
+	checkParamError(t, err, "api")
+}
+
+func TestCreateOrUpdateObjectReplace(t *testing.T) {
+	var attrs map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/objects/1/acme/attributes":
+			w.Write([]byte(`{"object":{"attributes":{"object_id":"acme","name":"Acme","stale":"x"}}}`))
+		case "/v1/objects/1/broken/attributes":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/api/v2/batch":
+			var body struct {
+				Batch []map[string]any `json:"batch"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			attrs = body.Batch[0]["attributes"].(map[string]any)
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	track := customerio.NewTrackClient("siteid", "apikey", customerio.WithAPIClient(api))
+	track.URL = srv.URL
+
+	err := track.CreateOrUpdateObject(context.Background(), "1", "acme", map[string]any{"name": "Acme Inc"}, customerio.ObjectWriteReplace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"name": "Acme Inc", "stale": ""}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("wrong attributes. got: %v, want: %v", attrs, want)
+	}
+
+	err = track.CreateOrUpdateObject(context.Background(), "1", "broken", map[string]any{"name": "Broken"}, customerio.ObjectWriteReplace)
+	if err == nil || !strings.Contains(err.Error(), "/v1/objects/1/broken/attributes") {
+		t.Errorf("expected an error naming the attributes request, got: %v", err)
+	}
+
+	// Merging doesn't read the object, so it needs no App API client.
+	merger := customerio.NewTrackClient("siteid", "apikey")
+	merger.URL = srv.URL
+	if err := merger.CreateOrUpdateObject(context.Background(), "1", "acme", map[string]any{"name": "Acme"}, customerio.ObjectWriteMerge); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"name": "Acme"}; !reflect.DeepEqual(attrs, want) {
+		t.Errorf("wrong attributes. got: %v, want: %v", attrs, want)
+	}
+	err = merger.CreateOrUpdateObject(context.Background(), "1", "acme", map[string]any{"name": "Acme"}, customerio.ObjectWriteReplace)
+	checkParamError(t, err, "api")
+}
+
+func TestFindCustomObjectsLimit(t *testing.T) {
+	var limits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/v1/objects" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		var body struct {
+			ObjectTypeID string         `json:"object_type_id"`
+			Filter       map[string]any `json:"filter"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if body.ObjectTypeID != "1" {
+			t.Errorf("wrong object type. got: %s, want: 1", body.ObjectTypeID)
+		}
+		limits = append(limits, req.URL.Query().Get("limit"))
+		if req.URL.Query().Get("start") == "" {
+			w.Write([]byte(`{"ids":["a","b"],"next":"page2"}`))
+			return
+		}
+		w.Write([]byte(`{"ids":["c"],"next":"page3"}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	ids, err := api.FindCustomObjectsLimit(context.Background(), "1", map[string]any{"and": []any{}}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("wrong ids. got: %v, want: %v", ids, want)
+	}
+	if want := []string{"3", "1"}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("wrong page sizes. got: %v, want: %v", limits, want)
+	}
+}
+
+func TestGetObjectTypeSchema(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/objects":
+			if got := req.URL.Query().Get("limit"); got != "20" {
+				t.Errorf("wrong sample size. got: %s, want: 20", got)
+			}
+			checkAllObjectsFilter(t, req)
+			w.Write([]byte(`{"ids":["a","b"],"next":""}`))
+		case "/v1/objects/1/a/attributes":
+			w.Write([]byte(`{"object":{"attributes":{"name":"A","seats":10,"active":true}}}`))
+		case "/v1/objects/1/b/attributes":
+			w.Write([]byte(`{"object":{"attributes":{"name":"B","seats":"many"}}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	definitions, err := api.GetObjectTypeSchema(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []customerio.AttributeDefinition{
+		{Name: "active", Type: "boolean", Required: false},
+		{Name: "name", Type: "string", Required: true},
+		{Name: "seats", Type: "mixed", Required: true},
+	}
+	if !reflect.DeepEqual(definitions, want) {
+		t.Errorf("wrong definitions. got: %v, want: %v", definitions, want)
+	}
+}
+
+// checkAllObjectsFilter checks that an object search matches every object of
+// its type.
+func checkAllObjectsFilter(t *testing.T, req *http.Request) {
+	t.Helper()
+	var body struct {
+		Filter map[string]any `json:"filter"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		t.Error(err)
+	}
+	want := map[string]any{"and": []any{map[string]any{
+		"object_attribute": map[string]any{"field": "object_id", "operator": "exists"},
+	}}}
+	if !reflect.DeepEqual(body.Filter, want) {
+		t.Errorf("wrong filter. got: %v, want: %v", body.Filter, want)
+	}
+}
+
+func TestListObjectTypesWithCounts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/v1/object_types":
+			w.Write([]byte(`{"types":[{"id":"1","name":"Companies"},{"id":"2","name":"Accounts"},{"id":"3","name":"Empty"}]}`))
+		case req.URL.Path == "/v1/objects":
+			checkAllObjectsFilter(t, req)
+			switch req.URL.Query().Get("start") {
+			case "":
+				w.Write([]byte(`{"ids":["a","b"],"next":"page2"}`))
+			default:
+				w.Write([]byte(`{"ids":["c"],"next":""}`))
+			}
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	stats, err := api.ListObjectTypesWithCounts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("wrong number of types. got: %d, want: 3", len(stats))
+	}
+	for i, id := range []string{"1", "2", "3"} {
+		if stats[i].ID != id || stats[i].Count != 3 {
+			t.Errorf("wrong stat %d. got: %s=%d, want: %s=3", i, stats[i].ID, stats[i].Count, id)
+		}
+	}
+}
+
+func TestListObjectTypesWithCountsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/object_types":
+			w.Write([]byte(`{"types":[{"id":"1"},{"id":"2"}]}`))
+		case "/v1/objects":
+			var body struct {
+				ObjectTypeID string `json:"object_type_id"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			if body.ObjectTypeID == "2" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"ids":["a"],"next":""}`))
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	stats, err := api.ListObjectTypesWithCounts(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "object type 2") {
+		t.Errorf("expected an error for object type 2, got: %v", err)
+	}
+	if stats != nil {
+		t.Errorf("expected no stats, got: %v", stats)
+	}
+}
+
+func TestFindCustomObjectsWithAttributes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/objects":
+			w.Write([]byte(`{"ids":["a","b","c"],"next":""}`))
+		case "/v1/objects/1/a/attributes":
+			w.Write([]byte(`{"object":{"attributes":{"name":"A"}}}`))
+		case "/v1/objects/1/c/attributes":
+			w.Write([]byte(`{"object":{"attributes":{"name":"C"}}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	objects, err := api.FindCustomObjectsWithAttributes(context.Background(), "1", map[string]any{"and": []any{}})
+	if err == nil || !strings.Contains(err.Error(), "object b") {
+		t.Errorf("expected error naming object b, got: %v", err)
+	}
+	want := []customerio.Object{
+		{TypeID: "1", ID: "a", Attributes: map[string]any{"name": "A"}},
+		{TypeID: "1", ID: "c", Attributes: map[string]any{"name": "C"}},
+	}
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("wrong objects. got: %v, want: %v", objects, want)
+	}
+}
+
+func TestDeleteObjectType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	checkParamError(t, api.DeleteObjectType(ctx, ""), "objectTypeID")
+	if err := api.DeleteObjectType(ctx, "1"); err != customerio.ErrNotSupported {
+		t.Errorf("expected ErrNotSupported, got: %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,6 +26,12 @@ type Customer struct {
 	Email        string                 `json:"email,omitempty"`
 	ID           string                 `json:"id,omitempty"`
 	Unsubscribed *bool                  `json:"unsubscribed,omitempty"`
+	Timezone     string                 `json:"timezone,omitempty"`
+
+	// LastEmailed and LastVisited are maintained by Customer.io, and are
+	// only populated when reading a customer.
+	LastEmailed *time.Time `json:"_last_emailed,omitempty"`
+	LastVisited *time.Time `json:"_last_visited,omitempty"`
 }
 
 type attributesResponse struct {
@@ -36,6 +43,9 @@ type attributesResponse struct {
 			Email        string `json:"email"`
 			ID           string `json:"id"`
 			Unsubscribed string `json:"unsubscribed"`
+			Timezone     string `json:"timezone"`
+			LastEmailed  string `json:"_last_emailed"`
+			LastVisited  string `json:"_last_visited"`
 		} `json:"attributes"`
 	} `json:"customer"`
 }
@@ -44,7 +54,7 @@ func (c *APIClient) GetCustomer(ctx context.Context, id string, idType Identifie
 	v := url.Values{}
 	v.Add("id_type", string(idType))
 	qs := v.Encode()
-	url := fmt.Sprintf("/v1/customers/%s/attributes?%s", id, qs)
+	url := fmt.Sprintf("/v1/customers/%s/attributes?%s", url.PathEscape(id), qs)
 	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return Customer{}, err
@@ -69,22 +79,28 @@ func (c *APIClient) GetCustomer(ctx context.Context, id string, idType Identifie
 		}
 	}
 
-	var thyme *time.Time
-	if resp.Customer.Attributes.CreatedAt != "" {
-		createdInt, err := strconv.Atoi(resp.Customer.Attributes.CreatedAt)
-		if err != nil {
-			return Customer{}, err
-		}
-		unixS := time.Unix(int64(createdInt), 0)
-		thyme = &unixS
+	thyme, err := parseCustomerIOTime(resp.Customer.Attributes.CreatedAt)
+	if err != nil {
+		return Customer{}, err
+	}
+	lastEmailed, err := parseCustomerIOTime(resp.Customer.Attributes.LastEmailed)
+	if err != nil {
+		return Customer{}, err
+	}
+	lastVisited, err := parseCustomerIOTime(resp.Customer.Attributes.LastVisited)
+	if err != nil {
+		return Customer{}, err
 	}
 
 	cust := Customer{
-		Attributes: attributes,
-		CioID:      resp.Customer.Attributes.CioID,
-		CreatedAt:  thyme,
-		Email:      resp.Customer.Attributes.Email,
-		ID:         resp.Customer.Attributes.ID,
+		Attributes:  attributes,
+		CioID:       resp.Customer.Attributes.CioID,
+		CreatedAt:   thyme,
+		Email:       resp.Customer.Attributes.Email,
+		ID:          resp.Customer.Attributes.ID,
+		Timezone:    resp.Customer.Attributes.Timezone,
+		LastEmailed: lastEmailed,
+		LastVisited: lastVisited,
 	}
 	if resp.Customer.Attributes.Unsubscribed != "" {
 		subbed := resp.Customer.Attributes.Unsubscribed == "true"
@@ -93,21 +109,78 @@ func (c *APIClient) GetCustomer(ctx context.Context, id string, idType Identifie
 	return cust, nil
 }
 
+// GetCustomerByIdentifier returns the customer identified by id, whichever
+// type of identifier it is. Emails are first resolved to a profile with the
+// email search, since the same email may belong to several profiles; if it
+// does, an *EmailConflictError lists them.
+func (c *APIClient) GetCustomerByIdentifier(ctx context.Context, id Identifier) (Customer, error) {
+	if err := id.Validate(); err != nil {
+		return Customer{}, err
+	}
+	if id.Type != IdentifierTypeEmail {
+		return c.GetCustomer(ctx, id.Value, id.Type)
+	}
+
+	cioIDs, err := c.LookupCustomersByEmail(ctx, id.Value)
+	if err != nil {
+		return Customer{}, err
+	}
+	switch len(cioIDs) {
+	case 0:
+		return Customer{}, ErrCustomerNotFound
+	case 1:
+		return c.GetCustomer(ctx, cioIDs[0], IdentifierTypeCioID)
+	}
+	return Customer{}, &EmailConflictError{Email: id.Value, CioIDs: cioIDs}
+}
+
+// CustomerExists reports whether a customer with the given identifier exists,
+// without decoding their attributes. The App API has no HEAD support for
+// customers, so this issues the attributes request and only inspects its
+// status.
+func (c *APIClient) CustomerExists(ctx context.Context, id string, idType IdentifierType) (bool, error) {
+	v := url.Values{}
+	v.Add("id_type", string(idType))
+	url := fmt.Sprintf("/v1/customers/%s/attributes?%s", url.PathEscape(id), v.Encode())
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, &CustomerIOError{status: statusCode, url: url, body: body}
+}
+
 type customerSearchRequest struct {
-	Filter filterCondition `json:"filter"`
+	Filter Filter `json:"filter"`
 }
-type filterCondition struct {
-	Or  []attributeCondition `json:"or,omitempty"`
-	And []attributeCondition `json:"and,omitempty"`
+
+// Filter is a customer search filter. A customer matches when it satisfies
+// every And condition, or any Or condition. Conditions are built with
+// NewEqAttribute and its siblings.
+type Filter struct {
+	Or  []AttributeCondition `json:"or,omitempty"`
+	And []AttributeCondition `json:"and,omitempty"`
+}
+
+func (f Filter) empty() bool {
+	return len(f.Or) == 0 && len(f.And) == 0
 }
-type attributeCondition struct {
+
+// AttributeCondition is a single condition of a Filter.
+type AttributeCondition struct {
 	Attribute attribute `json:"attribute"`
 }
 
 type attribute struct {
-	Field    string `json:"field"`
-	Operator string `json:"operator"`
-	Value    string `json:"value"`
+	Field    string      `json:"field"`
+	Operator string      `json:"operator"`
+	Value    interface{} `json:"value,omitempty"`
 }
 
 type searchResponse struct {
@@ -116,8 +189,8 @@ type searchResponse struct {
 
 // NewEqAttribute takes a field and string and produces an Equality
 // AttributeCondition
-func NewEqAttribute(field string, value string) attributeCondition {
-	return attributeCondition{
+func NewEqAttribute(field string, value string) AttributeCondition {
+	return AttributeCondition{
 		Attribute: attribute{
 			Field:    field,
 			Operator: "eq",
@@ -126,23 +199,66 @@ func NewEqAttribute(field string, value string) attributeCondition {
 	}
 }
 
+// NewEqAttributeValue produces an equality AttributeCondition. Unlike
+// NewEqAttribute, value is encoded with its JSON type, so numbers and
+// booleans are compared as such rather than as strings.
+func NewEqAttributeValue(field string, value interface{}) AttributeCondition {
+	return newAttributeCondition(field, "eq", value)
+}
+
+// NewGtAttribute produces an AttributeCondition matching values of field
+// greater than value.
+func NewGtAttribute(field string, value interface{}) AttributeCondition {
+	return newAttributeCondition(field, "gt", value)
+}
+
+// NewLtAttribute produces an AttributeCondition matching values of field less
+// than value.
+func NewLtAttribute(field string, value interface{}) AttributeCondition {
+	return newAttributeCondition(field, "lt", value)
+}
+
+// NewExistsAttribute produces an AttributeCondition matching customers that
+// have any value for field.
+func NewExistsAttribute(field string) AttributeCondition {
+	return newAttributeCondition(field, "exists", nil)
+}
+
+func newAttributeCondition(field, operator string, value interface{}) AttributeCondition {
+	return AttributeCondition{
+		Attribute: attribute{
+			Field:    field,
+			Operator: operator,
+			Value:    value,
+		},
+	}
+}
+
 // LookupCustomerIds takes a list of emails/ids/cio ids and returns a list of
-// the same size with the valid (if any) cio ids.
+// the same size with the valid (if any) cio ids. Ids are looked up with one
+// search request per 1k ids.
 func (c *APIClient) LookupCustomerioIds(ctx context.Context, ids []string, idType IdentifierType) ([]string, error) {
-	// A better thing to do would be to split these into es and then issue
-	// requests, one per 1k results. This is just a nicety at this point, so
-	// I'll leave that for another time.
-	if len(ids) > 1000 {
-		return nil, errors.New("Can only lookup 1k customers at a time")
+	result := make([]string, 0, len(ids))
+	for start := 0; start < len(ids); start += maxPageSize {
+		chunk, err := c.lookupCustomerioIdsChunk(ctx, ids[start:min(start+maxPageSize, len(ids))], idType)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, chunk...)
 	}
-	conditions := make([]attributeCondition, len(ids))
+	return result, nil
+}
+
+// lookupCustomerioIdsChunk looks up at most 1k ids with a single search.
+func (c *APIClient) lookupCustomerioIdsChunk(ctx context.Context, ids []string, idType IdentifierType) ([]string, error) {
+	conditions := make([]AttributeCondition, len(ids))
 	for i, id := range ids {
 		conditions[i] = NewEqAttribute(string(idType), id)
 	}
 	payload := customerSearchRequest{
-		Filter: filterCondition{Or: conditions},
+		Filter: Filter{Or: conditions},
 	}
-	url := "/v1/customers?limit=1000"
+	url := "/v1/customers?" + pageValues("", maxPageSize).Encode()
 	body, statusCode, err := c.doRequest(ctx, "POST", url, payload)
 	if err != nil {
 		return nil, err
@@ -205,3 +321,427 @@ func (c *APIClient) LookupCustomersByEmail(ctx context.Context, email string) ([
 	}
 	return cioids, nil
 }
+
+// CustomerIterator iterates over customers, fetching pages as needed.
+type CustomerIterator interface {
+	// Next advances to the next customer, returning false when there are no
+	// more customers or an error occurred.
+	Next() bool
+	// Customer returns the current customer.
+	Customer() Customer
+	// Err returns the error that stopped iteration, if any.
+	Err() error
+}
+
+// allCustomersPageSize is the maximum page size accepted by customer search.
+const allCustomersPageSize = 1000
+
+type customerSearchIterator struct {
+	ctx    context.Context
+	client *APIClient
+	page   []Customer
+	next   string
+	cur    Customer
+	err    error
+}
+
+// AllCustomers returns an iterator over every customer in the workspace,
+// using a search that matches any profile with a cio_id. Customers only have
+// their identifiers populated; use GetCustomer to read attributes. The first
+// page is fetched before returning, so an invalid key or unreachable API is
+// reported immediately. Customer search returns at most 10,000 results for
+// a filter, so in larger workspaces iteration ends after the first 10,000
+// customers; use the exports API for a complete export of those.
+func (c *APIClient) AllCustomers(ctx context.Context) (CustomerIterator, error) {
+	it := &customerSearchIterator{ctx: ctx, client: c}
+	if err := it.fetch(); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// allCustomersFilter matches every customer profile.
+var allCustomersFilter = Filter{
+	And: []AttributeCondition{NewExistsAttribute("cio_id")},
+}
+
+func (it *customerSearchIterator) fetch() error {
+	page, err := it.client.SearchCustomersPage(it.ctx, allCustomersFilter, it.next, allCustomersPageSize)
+	if err != nil {
+		return err
+	}
+	it.page = page.Items
+	it.next = page.NextCursor
+	if len(page.Items) == 0 {
+		it.next = ""
+	}
+	return nil
+}
+
+// jsonID is an identifier that the API may encode as either a string or a
+// number.
+type jsonID string
+
+func (id *jsonID) UnmarshalJSON(b []byte) error {
+	var s *string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s != nil {
+			*id = jsonID(*s)
+		}
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*id = jsonID(n.String())
+	return nil
+}
+
+// SearchCustomersPage returns a single page of the customers matching filter,
+// starting at cursor, or at the first page if cursor is empty. Customers only
+// have their identifiers populated. A limit of zero or less uses the API's
+// default page size.
+func (c *APIClient) SearchCustomersPage(ctx context.Context, filter Filter, cursor string, limit int) (PageResult[Customer], error) {
+	url := "/v1/customers?" + pageValues(cursor, limit).Encode()
+	body, statusCode, err := c.doRequest(ctx, "POST", url, customerSearchRequest{Filter: filter})
+	if err != nil {
+		return PageResult[Customer]{}, err
+	}
+	if statusCode != http.StatusOK {
+		return PageResult[Customer]{}, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var resp struct {
+		Identifiers []struct {
+			CioID jsonID `json:"cio_id"`
+			Email string `json:"email"`
+			ID    jsonID `json:"id"`
+		} `json:"identifiers"`
+		Next string `json:"next"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return PageResult[Customer]{}, err
+	}
+
+	customers := make([]Customer, len(resp.Identifiers))
+	for i, ident := range resp.Identifiers {
+		customers[i] = Customer{CioID: string(ident.CioID), Email: ident.Email, ID: string(ident.ID)}
+	}
+	return PageResult[Customer]{Items: customers, NextCursor: resp.Next}, nil
+}
+
+// ResolvedIdentifiers are all of the identifiers of a customer returned by a
+// search. Fields the customer doesn't have are empty.
+type ResolvedIdentifiers struct {
+	CioID string
+	ID    string
+	Email string
+}
+
+// SearchIdentifiers returns the identifiers of every customer matching
+// filter, following pagination until every match has been read.
+func (c *APIClient) SearchIdentifiers(ctx context.Context, filter Filter) ([]ResolvedIdentifiers, error) {
+	if filter.empty() {
+		return nil, ParamError{Param: "filter"}
+	}
+
+	customers, err := collectPages(0, func(cursor string, pageSize int) (PageResult[Customer], error) {
+		return c.SearchCustomersPage(ctx, filter, cursor, pageSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]ResolvedIdentifiers, len(customers))
+	for i, customer := range customers {
+		ids[i] = ResolvedIdentifiers{CioID: customer.CioID, ID: customer.ID, Email: customer.Email}
+	}
+	return ids, nil
+}
+
+func (it *customerSearchIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for len(it.page) == 0 {
+		if it.next == "" {
+			return false
+		}
+		if it.err = it.fetch(); it.err != nil {
+			return false
+		}
+	}
+	it.cur, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *customerSearchIterator) Customer() Customer {
+	return it.cur
+}
+
+func (it *customerSearchIterator) Err() error {
+	return it.err
+}
+
+// JourneyMembership describes a campaign a customer is currently in.
+type JourneyMembership struct {
+	CampaignID  int        `json:"campaign_id"`
+	CurrentStep string     `json:"current_step"`
+	EnteredAt   *time.Time `json:"entered_at"`
+}
+
+func (j *JourneyMembership) UnmarshalJSON(b []byte) error {
+	var r struct {
+		CampaignID  int             `json:"campaign_id"`
+		CurrentStep string          `json:"current_step"`
+		EnteredAt   json.RawMessage `json:"entered_at"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	enteredAt, err := parseCustomerIOTime(r.EnteredAt)
+	if err != nil {
+		return err
+	}
+	j.CampaignID = r.CampaignID
+	j.CurrentStep = r.CurrentStep
+	j.EnteredAt = enteredAt
+	return nil
+}
+
+// GetCustomerJourneys returns the campaigns the customer is currently in.
+func (c *APIClient) GetCustomerJourneys(ctx context.Context, id string, idType IdentifierType) ([]JourneyMembership, error) {
+	v := url.Values{}
+	v.Add("id_type", string(idType))
+	url := fmt.Sprintf("/v1/customers/%s/journeys?%s", url.PathEscape(id), v.Encode())
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode == http.StatusNotFound {
+		return nil, ErrCustomerNotFound
+	} else if statusCode != http.StatusOK {
+		return nil, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var resp struct {
+		Journeys []JourneyMembership `json:"journeys"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Journeys, nil
+}
+
+// SearchCustomersByEmailPrefix returns up to limit customers whose email
+// starts with prefix, compared case-insensitively since Customer.io stores
+// emails lowercased. A limit of zero or less returns every match. Customers
+// only have their identifiers populated.
+//
+// The search filter only documents exact and existence matches, so there is
+// no server-side prefix match: this searches every customer with an email, a
+// page at a time, and keeps the ones whose email matches. It is slow in large
+// workspaces and counts against the App API rate limit for each page, and
+// since search returns at most 10,000 results for a filter, customers past
+// the first 10,000 with an email are never considered. Use
+// LookupCustomersByEmail for an exact email.
+func (c *APIClient) SearchCustomersByEmailPrefix(ctx context.Context, prefix string, limit int) ([]Customer, error) {
+	if strings.TrimSpace(prefix) == "" {
+		return nil, ParamError{Param: "prefix"}
+	}
+	prefix = strings.ToLower(prefix)
+	filter := Filter{And: []AttributeCondition{NewExistsAttribute("email")}}
+
+	var (
+		matches []Customer
+		cursor  string
+	)
+	for {
+		page, err := c.SearchCustomersPage(ctx, filter, cursor, maxPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, customer := range page.Items {
+			if strings.HasPrefix(strings.ToLower(customer.Email), prefix) {
+				matches = append(matches, customer)
+				if limit > 0 && len(matches) == limit {
+					return matches, nil
+				}
+			}
+		}
+		if page.NextCursor == "" || len(page.Items) == 0 {
+			return matches, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// customerAttributes returns the customer's stored attributes as returned by
+// the attributes endpoint, without interpreting them.
+func (c *APIClient) customerAttributes(ctx context.Context, id string, idType IdentifierType) (map[string]interface{}, error) {
+	v := url.Values{}
+	v.Add("id_type", string(idType))
+	url := fmt.Sprintf("/v1/customers/%s/attributes?%s", url.PathEscape(id), v.Encode())
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode == http.StatusNotFound {
+		return nil, ErrCustomerNotFound
+	} else if statusCode != http.StatusOK {
+		return nil, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var resp struct {
+		Customer struct {
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"customer"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Customer.Attributes, nil
+}
+
+// VerifyIdentify reads back the attributes stored for the customer, identified
+// by id, and returns those keys of expected whose stored values differ, mapped
+// to the stored value, or nil if the attribute is not set. Values are compared
+// the way Customer.io stores them: numbers and booleans match their string
+// forms, and emails match case-insensitively. An empty map means every
+// expected attribute landed as intended.
+func (c *APIClient) VerifyIdentify(ctx context.Context, customerID string, expected map[string]interface{}) (map[string]interface{}, error) {
+	if customerID == "" {
+		return nil, ParamError{Param: "customerID"}
+	}
+	stored, err := c.customerAttributes(ctx, customerID, IdentifierTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]interface{}{}
+	for k, want := range expected {
+		got, ok := stored[k]
+		if !ok || !attributeEqual(k, want, got) {
+			diff[k] = got
+		}
+	}
+	return diff, nil
+}
+
+// attributeEqual reports whether a value sent for attribute key matches the
+// value Customer.io stored for it.
+func attributeEqual(key string, sent, stored interface{}) bool {
+	a, b := attributeString(sent), attributeString(stored)
+	if key == "email" {
+		return strings.EqualFold(a, b)
+	}
+	if a == b {
+		return true
+	}
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	return errA == nil && errB == nil && fa == fb
+}
+
+func attributeString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// lookupConcurrency bounds the attribute requests made concurrently by
+// GetCustomersByEmails and GetCustomersByCioIDs.
+const lookupConcurrency = 8
+
+// GetCustomersByEmails returns the full customer record for each of the
+// emails, keyed by the lowercased email. Emails are resolved to customers
+// with one search request per 1k emails, then each customer's attributes are
+// fetched with a bounded number of requests in flight. Emails without a
+// matching customer are absent from the map. If some customers can't be
+// fetched, the ones that were are returned along with an error naming each
+// email that failed; if the emails can't be resolved, no customers are
+// returned.
+func (c *APIClient) GetCustomersByEmails(ctx context.Context, emails []string) (map[string]Customer, error) {
+	cioIDs, err := c.LookupCustomerioIds(ctx, emails, IdentifierTypeEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	byEmail := map[string]string{}
+	for i, cioID := range cioIDs {
+		email := strings.ToLower(emails[i])
+		if _, ok := byEmail[email]; cioID != "" && !ok {
+			byEmail[email] = cioID
+		}
+	}
+
+	return c.getCustomersByCioID(ctx, byEmail)
+}
+
+// GetCustomersByCioIDs returns the full customer record for each of the
+// cio_ids, keyed by cio_id, fetching their attributes with a bounded number
+// of requests in flight. Ids without a matching customer are absent from the
+// map. If some customers can't be fetched, the ones that were are returned
+// along with an error naming each id that failed.
+func (c *APIClient) GetCustomersByCioIDs(ctx context.Context, cioIDs []string) (map[string]Customer, error) {
+	byID := map[string]string{}
+	for _, cioID := range cioIDs {
+		if cioID != "" {
+			byID[cioID] = cioID
+		}
+	}
+	return c.getCustomersByCioID(ctx, byID)
+}
+
+// getCustomersByCioID fetches the customer for each cio_id in ids, returning
+// them under the corresponding key. At most lookupConcurrency requests are
+// in flight, and no more are started once ctx is done.
+func (c *APIClient) getCustomersByCioID(ctx context.Context, ids map[string]string) (map[string]Customer, error) {
+	var (
+		mu        sync.Mutex
+		customers = map[string]Customer{}
+		errs      []error
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, lookupConcurrency)
+	)
+	for key, cioID := range ids {
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key, cioID string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			customer, err := c.GetCustomer(ctx, cioID, IdentifierTypeCioID)
+			mu.Lock()
+			defer mu.Unlock()
+			if errors.Is(err, ErrCustomerNotFound) {
+				return
+			} else if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				return
+			}
+			customers[key] = customer
+		}(key, cioID)
+	}
+	wg.Wait()
+
+	return customers, errors.Join(errs...)
+}
@@ -0,0 +1,566 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestAttributeConditionValues(t *testing.T) {
+	cases := []struct {
+		name string
+		cond interface{}
+		want string
+	}{
+		{"string", customerio.NewEqAttribute("plan", "pro"), `{"attribute":{"field":"plan","operator":"eq","value":"pro"}}`},
+		{"string value", customerio.NewEqAttributeValue("plan", "10"), `{"attribute":{"field":"plan","operator":"eq","value":"10"}}`},
+		{"number", customerio.NewEqAttributeValue("seats", 10), `{"attribute":{"field":"seats","operator":"eq","value":10}}`},
+		{"bool", customerio.NewEqAttributeValue("active", true), `{"attribute":{"field":"active","operator":"eq","value":true}}`},
+		{"gt", customerio.NewGtAttribute("seats", 2.5), `{"attribute":{"field":"seats","operator":"gt","value":2.5}}`},
+		{"lt", customerio.NewLtAttribute("seats", 100), `{"attribute":{"field":"seats","operator":"lt","value":100}}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := json.Marshal(c.cond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != c.want {
+				t.Errorf("wrong encoding. got: %s, want: %s", b, c.want)
+			}
+		})
+	}
+}
+
+func TestVerifyIdentify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/customers/1/attributes" {
+			t.Errorf("unexpected path: %s", req.URL.Path)
+		}
+		w.Write([]byte(`{"customer":{"attributes":{"email":"person@example.com","seats":"10","active":"true","plan":"basic"}}}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	diff, err := api.VerifyIdentify(context.Background(), "1", map[string]interface{}{
+		"email":  "Person@Example.com",
+		"seats":  10.0,
+		"active": true,
+		"plan":   "pro",
+		"region": "eu",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"plan": "basic", "region": nil}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("wrong diff. got: %#v, want: %#v", diff, want)
+	}
+}
+
+func TestGetCustomerSystemAttributes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/customers/1/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"1","cio_id":"a","timezone":"America/New_York","_last_emailed":"1600000000","unsubscribed":"false"}}}`))
+		case "/v1/customers/2/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"2","cio_id":"b","unsubscribed":"true"}}}`))
+		default:
+			w.Write([]byte(`{"customer":{"attributes":{"id":"3","cio_id":"c"}}}`))
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	customer, err := api.GetCustomer(context.Background(), "1", customerio.IdentifierTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Timezone != "America/New_York" {
+		t.Errorf("wrong timezone. got: %q, want: %q", customer.Timezone, "America/New_York")
+	}
+	if customer.LastEmailed == nil || customer.LastEmailed.Unix() != 1600000000 {
+		t.Errorf("wrong last emailed. got: %v, want: %v", customer.LastEmailed, 1600000000)
+	}
+	if customer.LastVisited != nil {
+		t.Errorf("wrong last visited. got: %v, want: nil", customer.LastVisited)
+	}
+	if customer.Unsubscribed == nil || *customer.Unsubscribed {
+		t.Errorf("wrong unsubscribed. got: %v, want: false", customer.Unsubscribed)
+	}
+
+	customer, err = api.GetCustomer(context.Background(), "2", customerio.IdentifierTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Unsubscribed == nil || !*customer.Unsubscribed {
+		t.Errorf("wrong unsubscribed. got: %v, want: true", customer.Unsubscribed)
+	}
+
+	customer, err = api.GetCustomer(context.Background(), "3", customerio.IdentifierTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.Unsubscribed != nil || customer.LastEmailed != nil || customer.Timezone != "" {
+		t.Errorf("expected unset system attributes. got: %v, %v, %q", customer.Unsubscribed, customer.LastEmailed, customer.Timezone)
+	}
+}
+
+func TestGetCustomersByCioIDs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/customers/a/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"1","cio_id":"a"}}}`))
+		case "/v1/customers/missing/attributes":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	customers, err := api.GetCustomersByCioIDs(context.Background(), []string{"a", "missing", "broken"})
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected error naming the failed id, got: %v", err)
+	}
+	if len(customers) != 1 || customers["a"].ID != "1" {
+		t.Errorf("wrong customers. got: %v, want: only a", customers)
+	}
+}
+
+func TestCustomerExists(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got := req.URL.Query().Get("id_type"); got != "email" {
+			t.Errorf("wrong id_type. got: %s, want: email", got)
+		}
+		switch req.URL.EscapedPath() {
+		case "/v1/customers/a%2Fb@example.com/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"email":"a/b@example.com"}}}`))
+		case "/v1/customers/missing@example.com/attributes":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	if exists, err := api.CustomerExists(ctx, "a/b@example.com", customerio.IdentifierTypeEmail); err != nil || !exists {
+		t.Errorf("expected existing customer, got: %v, %v", exists, err)
+	}
+	if exists, err := api.CustomerExists(ctx, "missing@example.com", customerio.IdentifierTypeEmail); err != nil || exists {
+		t.Errorf("expected missing customer, got: %v, %v", exists, err)
+	}
+	if _, err := api.CustomerExists(ctx, "broken@example.com", customerio.IdentifierTypeEmail); err == nil {
+		t.Error("expected error for a failed request")
+	}
+}
+
+func TestAllCustomers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/v1/customers" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if want := `{"filter":{"and":[{"attribute":{"field":"cio_id","operator":"exists"}}]}}`; string(b) != want {
+			t.Errorf("wrong filter.\ngot:  %s\nwant: %s", b, want)
+		}
+		if got := req.URL.Query().Get("limit"); got != "1000" {
+			t.Errorf("wrong page size. got: %s, want: 1000", got)
+		}
+		switch req.URL.Query().Get("start") {
+		case "":
+			w.Write([]byte(`{"identifiers":[{"cio_id":"a","id":1,"email":"a@example.com"},{"cio_id":"b","id":"two"}],"next":"page2"}`))
+		case "page2":
+			w.Write([]byte(`{"identifiers":[{"cio_id":"c","id":null}],"next":"page3"}`))
+		default:
+			w.Write([]byte(`{"identifiers":[],"next":""}`))
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	it, err := api.AllCustomers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []customerio.Customer
+	for it.Next() {
+		got = append(got, it.Customer())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []customerio.Customer{
+		{CioID: "a", ID: "1", Email: "a@example.com"},
+		{CioID: "b", ID: "two"},
+		{CioID: "c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong customers. got: %v, want: %v", got, want)
+	}
+}
+
+func TestAllCustomersError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("start") == "" {
+			w.Write([]byte(`{"identifiers":[{"cio_id":"a"}],"next":"page2"}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	it, err := api.AllCustomers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if n != 1 || it.Err() == nil {
+		t.Errorf("expected one customer and then an error, got: %d, %v", n, it.Err())
+	}
+}
+
+func TestGetCustomerJourneys(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got := req.URL.Query().Get("id_type"); got != "cio_id" {
+			t.Errorf("wrong id_type. got: %s, want: cio_id", got)
+		}
+		switch req.URL.Path {
+		case "/v1/customers/a/journeys":
+			w.Write([]byte(`{"journeys":[{"campaign_id":4,"current_step":"wait","entered_at":1600000000},{"campaign_id":5,"current_step":"email"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	journeys, err := api.GetCustomerJourneys(ctx, "a", customerio.IdentifierTypeCioID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journeys) != 2 {
+		t.Fatalf("wrong number of journeys. got: %d, want: 2", len(journeys))
+	}
+	if j := journeys[0]; j.CampaignID != 4 || j.CurrentStep != "wait" || j.EnteredAt == nil || j.EnteredAt.Unix() != 1600000000 {
+		t.Errorf("wrong journey: %+v", j)
+	}
+	if j := journeys[1]; j.CampaignID != 5 || j.EnteredAt != nil {
+		t.Errorf("wrong journey: %+v", j)
+	}
+
+	if _, err := api.GetCustomerJourneys(ctx, "missing", customerio.IdentifierTypeCioID); err != customerio.ErrCustomerNotFound {
+		t.Errorf("expected ErrCustomerNotFound, got: %v", err)
+	}
+}
+
+func TestSearchCustomersByEmailPrefix(t *testing.T) {
+	var starts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/v1/customers" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if want := `{"filter":{"and":[{"attribute":{"field":"email","operator":"exists"}}]}}`; string(b) != want {
+			t.Errorf("wrong filter.\ngot:  %s\nwant: %s", b, want)
+		}
+		start := req.URL.Query().Get("start")
+		starts = append(starts, start)
+		switch start {
+		case "":
+			w.Write([]byte(`{"identifiers":[{"cio_id":"a","email":"ann@example.com"},{"cio_id":"b","email":"bob@example.com"}],"next":"p2"}`))
+		case "p2":
+			w.Write([]byte(`{"identifiers":[{"cio_id":"c","email":"joanna@example.com"},{"cio_id":"d","email":"anna@example.com"}],"next":"p3"}`))
+		default:
+			w.Write([]byte(`{"identifiers":[{"cio_id":"e","email":"annie@example.com"}],"next":""}`))
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	_, err := api.SearchCustomersByEmailPrefix(context.Background(), " ", 0)
+	checkParamError(t, err, "prefix")
+
+	customers, err := api.SearchCustomersByEmailPrefix(context.Background(), "Ann", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []customerio.Customer{{CioID: "a", Email: "ann@example.com"}, {CioID: "d", Email: "anna@example.com"}}
+	if !reflect.DeepEqual(customers, want) {
+		t.Errorf("wrong customers. got: %v, want: %v", customers, want)
+	}
+	if want := []string{"", "p2"}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("search should stop once limit matches are found. got pages: %q, want: %q", starts, want)
+	}
+
+	starts = nil
+	customers, err = api.SearchCustomersByEmailPrefix(context.Background(), "ann", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 3 {
+		t.Errorf("wrong number of matches. got: %d, want: 3", len(customers))
+	}
+}
+
+// lookupServer answers customer searches with a profile for each searched
+// email in matches, and attribute reads for those profiles. Reading the
+// profile with cio_id "broken" fails.
+func lookupServer(t *testing.T, matches map[string]string, searches *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "POST" && req.URL.Path == "/v1/customers" {
+			var body struct {
+				Filter struct {
+					Or []struct {
+						Attribute struct {
+							Value string `json:"value"`
+						} `json:"attribute"`
+					} `json:"or"`
+				} `json:"filter"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			if limit, _ := strconv.Atoi(req.URL.Query().Get("limit")); limit < len(body.Filter.Or) {
+				t.Errorf("page size %d is smaller than the %d ids searched", limit, len(body.Filter.Or))
+			}
+			*searches = append(*searches, len(body.Filter.Or))
+			var identifiers []map[string]string
+			for _, cond := range body.Filter.Or {
+				// Customer.io stores emails lowercased and matches them
+				// case-insensitively.
+				email := strings.ToLower(cond.Attribute.Value)
+				if cioID, ok := matches[email]; ok {
+					identifiers = append(identifiers, map[string]string{"email": email, "cio_id": cioID})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"identifiers": identifiers})
+			return
+		}
+		if req.URL.Path == "/v1/customers/broken/attributes" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for email, cioID := range matches {
+			if req.URL.Path == "/v1/customers/"+cioID+"/attributes" {
+				fmt.Fprintf(w, `{"customer":{"attributes":{"cio_id":%q,"email":%q}}}`, cioID, email)
+				return
+			}
+		}
+		t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
+
+func TestGetCustomersByEmails(t *testing.T) {
+	var searches []int
+	srv := lookupServer(t, map[string]string{"a0@example.com": "c0", "a1500@example.com": "c1500"}, &searches)
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	emails := make([]string, 1501)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("a%d@example.com", i)
+	}
+	emails[0] = "A0@Example.com"
+	customers, err := api.GetCustomersByEmails(context.Background(), emails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1000, 501}; !reflect.DeepEqual(searches, want) {
+		t.Errorf("wrong searches. got: %v, want: %v", searches, want)
+	}
+	if len(customers) != 2 || customers["a0@example.com"].CioID != "c0" || customers["a1500@example.com"].CioID != "c1500" {
+		t.Errorf("wrong customers. got: %v", customers)
+	}
+}
+
+func TestGetCustomersByEmailsPartialFailure(t *testing.T) {
+	var searches []int
+	srv := lookupServer(t, map[string]string{"ok@example.com": "c1", "bad@example.com": "broken"}, &searches)
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	customers, err := api.GetCustomersByEmails(context.Background(), []string{"ok@example.com", "bad@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "bad@example.com") {
+		t.Errorf("expected an error naming bad@example.com, got: %v", err)
+	}
+	if len(customers) != 1 || customers["ok@example.com"].CioID != "c1" {
+		t.Errorf("expected the customer that was fetched, got: %v", customers)
+	}
+}
+
+func TestGetCustomerByIdentifier(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.EscapedPath()+"?"+req.URL.RawQuery)
+		switch {
+		case req.URL.Path == "/v1/customers":
+			switch req.URL.Query().Get("email") {
+			case "one@example.com":
+				w.Write([]byte(`{"results":[{"cio_id":"a"}]}`))
+			case "shared@example.com":
+				w.Write([]byte(`{"results":[{"cio_id":"a"},{"cio_id":"b"}]}`))
+			default:
+				w.Write([]byte(`{"results":[]}`))
+			}
+		case strings.HasSuffix(req.URL.Path, "/attributes"):
+			fmt.Fprintf(w, `{"customer":{"attributes":{"id":%q,"cio_id":"a"}}}`, strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/v1/customers/"), "/attributes"))
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	cases := []struct {
+		id       customerio.Identifier
+		requests []string
+	}{
+		{customerio.Identifier{Type: customerio.IdentifierTypeID, Value: "1"},
+			[]string{"/v1/customers/1/attributes?id_type=id"}},
+		{customerio.Identifier{Type: customerio.IdentifierTypeCioID, Value: "a"},
+			[]string{"/v1/customers/a/attributes?id_type=cio_id"}},
+		{customerio.Identifier{Type: customerio.IdentifierTypeEmail, Value: "one@example.com"},
+			[]string{"/v1/customers?email=one%40example.com", "/v1/customers/a/attributes?id_type=cio_id"}},
+	}
+	for _, c := range cases {
+		requests = nil
+		if _, err := api.GetCustomerByIdentifier(ctx, c.id); err != nil {
+			t.Errorf("%s: %v", c.id.Value, err)
+		}
+		if !reflect.DeepEqual(requests, c.requests) {
+			t.Errorf("wrong requests for %s. got: %v, want: %v", c.id.Value, requests, c.requests)
+		}
+	}
+
+	_, err := api.GetCustomerByIdentifier(ctx, customerio.Identifier{Type: customerio.IdentifierTypeEmail, Value: "shared@example.com"})
+	want := &customerio.EmailConflictError{Email: "shared@example.com", CioIDs: []string{"a", "b"}}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("wrong error. got: %v, want: %v", err, want)
+	}
+
+	_, err = api.GetCustomerByIdentifier(ctx, customerio.Identifier{Type: customerio.IdentifierTypeEmail, Value: "nobody@example.com"})
+	if !errors.Is(err, customerio.ErrCustomerNotFound) {
+		t.Errorf("wrong error. got: %v, want: %v", err, customerio.ErrCustomerNotFound)
+	}
+}
+
+func TestGetCustomerEscapesID(t *testing.T) {
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.EscapedPath()
+		w.Write([]byte(`{"customer":{"attributes":{"id":"a/b c?d"}}}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	customer, err := api.GetCustomer(context.Background(), "a/b c?d", customerio.IdentifierTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/v1/customers/a%2Fb%20c%3Fd/attributes"; path != want {
+		t.Errorf("wrong path. got: %s, want: %s", path, want)
+	}
+	if customer.ID != "a/b c?d" {
+		t.Errorf("wrong id. got: %s, want: %s", customer.ID, "a/b c?d")
+	}
+}
+
+func TestSearchIdentifiers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/v1/customers" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		want := map[string]interface{}{"filter": map[string]interface{}{"or": []interface{}{
+			map[string]interface{}{"attribute": map[string]interface{}{"field": "plan", "operator": "eq", "value": "pro"}},
+			map[string]interface{}{"attribute": map[string]interface{}{"field": "seats", "operator": "gt", "value": float64(10)}},
+		}}}
+		if !reflect.DeepEqual(body, want) {
+			t.Errorf("wrong search body. got: %v, want: %v", body, want)
+		}
+		if req.URL.Query().Get("start") == "" {
+			w.Write([]byte(`{"identifiers":[{"cio_id":"a","id":"1","email":"a@example.com"},{"cio_id":"b","id":2}],"next":"page2"}`))
+			return
+		}
+		w.Write([]byte(`{"identifiers":[{"cio_id":"c","email":"c@example.com"}],"next":""}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	ids, err := api.SearchIdentifiers(context.Background(), customerio.Filter{Or: []customerio.AttributeCondition{
+		customerio.NewEqAttribute("plan", "pro"),
+		customerio.NewGtAttribute("seats", 10),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []customerio.ResolvedIdentifiers{
+		{CioID: "a", ID: "1", Email: "a@example.com"},
+		{CioID: "b", ID: "2"},
+		{CioID: "c", Email: "c@example.com"},
+	}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("wrong identifiers. got: %v, want: %v", ids, want)
+	}
+
+	_, err = api.SearchIdentifiers(context.Background(), customerio.Filter{})
+	checkParamError(t, err, "filter")
+}
@@ -7,11 +7,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const DefaultUserAgent = "Customer.io Go Client/" + Version
@@ -23,6 +24,18 @@ type CustomerIO struct {
 	URL       string
 	UserAgent string
 	Client    *http.Client
+
+	// API is the App API client used by the methods that read data before
+	// writing, such as CreateOrUpdateObject. It may be set with
+	// WithAPIClient.
+	API *APIClient
+
+	transportConfig
+
+	autoMillis         bool
+	strictPlatforms    bool
+	validateBatches    bool
+	validateAttributes bool
 }
 
 // CustomerIOError is returned by any method that fails at the API level
@@ -32,40 +45,97 @@ type CustomerIOError struct {
 	body   []byte
 }
 
+// maxErrorBodyLength is the most of a response body included in a
+// CustomerIOError's message.
+const maxErrorBodyLength = 512
+
 func (e *CustomerIOError) Error() string {
-	return fmt.Sprintf("%v: %v %v", e.status, e.url, string(e.body))
+	switch ct := e.ContentType(); ct {
+	case "":
+		return fmt.Sprintf("%v: upstream returned an empty body (%d)", e.url, e.status)
+	case "application/json", "text/plain":
+		body := e.body
+		if len(body) > maxErrorBodyLength {
+			return fmt.Sprintf("%v: %v %v...", e.status, e.url, strings.ToValidUTF8(string(body[:maxErrorBodyLength]), ""))
+		}
+		return fmt.Sprintf("%v: %v %v", e.status, e.url, string(body))
+	default:
+		return fmt.Sprintf("%v: upstream returned %s (%d)", e.url, ct, e.status)
+	}
+}
+
+// ContentType returns the media type of the response body, such as
+// "application/json" or "text/html", or an empty string if the body was
+// empty. Customer.io responds with JSON, so other types usually come from a
+// proxy in front of it. The type is detected from the body itself, since
+// the response headers aren't kept.
+func (e *CustomerIOError) ContentType() string {
+	if len(bytes.TrimSpace(e.body)) == 0 {
+		return ""
+	}
+	if json.Valid(e.body) {
+		return "application/json"
+	}
+	ct, _, _ := mime.ParseMediaType(http.DetectContentType(e.body))
+	return ct
 }
 
 // ParamError is an error returned if a parameter to the track API is invalid.
 type ParamError struct {
-	Param string // Param is the name of the parameter.
+	Param  string // Param is the name of the parameter.
+	Reason string // Reason describes the problem; empty means the parameter is missing.
 }
 
-func (e ParamError) Error() string { return e.Param + ": missing" }
+func (e ParamError) Error() string {
+	if e.Reason != "" {
+		return e.Param + ": " + e.Reason
+	}
+	return e.Param + ": missing"
+}
 
 // NewTrackClient prepares a client for use with the Customer.io track API, see: https://customer.io/docs/api/#apitrackintroduction
 // using a Tracking Site ID and API Key pair from https://fly.customer.io/settings/api_credentials
 func NewTrackClient(siteID, apiKey string, opts ...option) *CustomerIO {
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: 100,
+	}
 	client := &http.Client{
-		Transport: &http.Transport{
-			MaxIdleConnsPerHost: 100,
-		},
+		Transport: transport,
 	}
 	c := &CustomerIO{
-		siteID:    siteID,
-		apiKey:    apiKey,
-		URL:       "https://track.customer.io",
-		UserAgent: DefaultUserAgent,
-		Client:    client,
+		siteID:          siteID,
+		apiKey:          apiKey,
+		URL:             "https://track.customer.io",
+		UserAgent:       DefaultUserAgent,
+		Client:          client,
+		transportConfig: newTransportConfig(),
 	}
 
 	for _, opt := range opts {
 		opt.track(c)
 	}
+	if c.pool != nil && c.Client == client {
+		c.pool.configure(transport)
+	}
 
 	return c
 }
 
+// apiClient returns the App API client set with WithAPIClient, or a
+// ParamError if there is none.
+func (c *CustomerIO) apiClient() (*APIClient, error) {
+	if c.API == nil {
+		return nil, ParamError{Param: "api", Reason: "no App API client set; see WithAPIClient"}
+	}
+	return c.API, nil
+}
+
+// Close cancels any in-flight requests and closes idle connections. The
+// client can't be used after Close; later requests return ErrClientClosed.
+func (c *CustomerIO) Close() {
+	c.closeClient(c.Client)
+}
+
 // NewCustomerIO prepares a client for use with the Customer.io track API, see: https://customer.io/docs/api/#apitrackintroduction
 // deprecated in favour of NewTrackClient
 func NewCustomerIO(siteID, apiKey string) *CustomerIO {
@@ -74,13 +144,24 @@ func NewCustomerIO(siteID, apiKey string) *CustomerIO {
 
 // IdentifyCtx identifies a customer and sets their attributes
 func (c *CustomerIO) IdentifyCtx(ctx context.Context, customerID string, attributes map[string]interface{}) error {
+	_, err := c.IdentifyWithStatusCtx(ctx, customerID, attributes)
+	return err
+}
+
+// IdentifyWithStatusCtx is like IdentifyCtx but also returns the HTTP status
+// code of the successful response, for example to tell a newly created
+// profile from an update.
+func (c *CustomerIO) IdentifyWithStatusCtx(ctx context.Context, customerID string, attributes map[string]interface{}) (int, error) {
 	if customerID == "" {
-		return ParamError{Param: "customerID"}
+		return 0, ParamError{Param: "customerID"}
 	}
-	_, err := c.request(ctx, "PUT",
+	if err := c.checkAttributes("attributes", attributes); err != nil {
+		return 0, err
+	}
+	_, status, err := c.requestStatus(ctx, "PUT",
 		fmt.Sprintf("%s/api/v1/customers/%s", c.URL, url.PathEscape(customerID)),
 		attributes)
-	return err
+	return status, err
 }
 
 // Identify identifies a customer and sets their attributes
@@ -88,26 +169,140 @@ func (c *CustomerIO) Identify(customerID string, attributes map[string]interface
 	return c.IdentifyCtx(context.Background(), customerID, attributes)
 }
 
+// ErrCustomerExists is returned by CreateCustomerCtx when a customer with the
+// id already exists.
+var ErrCustomerExists = errors.New("customer already exists")
+
+// CreateCustomerCtx identifies a new customer, returning ErrCustomerExists
+// instead of updating them if a customer with the id already exists. The
+// Track API has no create-only mode, so the App API client set by
+// WithAPIClient is used to check for the customer first. The check and the
+// identify are separate requests: a customer created by someone else in
+// between is updated rather than reported, so this catches duplicates but
+// can't guarantee uniqueness.
+func (c *CustomerIO) CreateCustomerCtx(ctx context.Context, customerID string, attributes map[string]interface{}) error {
+	if customerID == "" {
+		return ParamError{Param: "customerID"}
+	}
+	api, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	exists, err := api.CustomerExists(ctx, customerID, IdentifierTypeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrCustomerExists
+	}
+	return c.IdentifyCtx(ctx, customerID, attributes)
+}
+
+// RefreshCustomer nudges Customer.io to re-evaluate a customer's segment
+// membership. There is no API to force re-evaluation, so this re-sends an
+// identify call without changing any attributes, which causes the customer's
+// profile to be reprocessed. Membership may still take a short while to
+// update afterwards.
+func (c *CustomerIO) RefreshCustomer(ctx context.Context, customerID string) error {
+	return c.IdentifyCtx(ctx, customerID, map[string]interface{}{})
+}
+
+// RecomputeRelationshipAttributes nudges Customer.io to recompute a
+// customer's attributes derived from their object relationships, which can
+// lag behind a relationship change. There is no API to force recomputation;
+// like RefreshCustomer, this re-sends an identify call without changing any
+// attributes, which causes the profile to be reprocessed. The attributes may
+// still take a short while to update afterwards.
+func (c *CustomerIO) RecomputeRelationshipAttributes(ctx context.Context, customerID string) error {
+	return c.RefreshCustomer(ctx, customerID)
+}
+
 // TrackCtx sends a single event to Customer.io for the supplied user
 func (c *CustomerIO) TrackCtx(ctx context.Context, customerID string, eventName string, data map[string]interface{}) error {
+	_, err := c.TrackWithStatusCtx(ctx, customerID, eventName, data)
+	return err
+}
+
+// TrackWithStatusCtx is like TrackCtx but also returns the HTTP status code
+// of the successful response.
+func (c *CustomerIO) TrackWithStatusCtx(ctx context.Context, customerID string, eventName string, data map[string]interface{}) (int, error) {
+	if customerID == "" {
+		return 0, ParamError{Param: "customerID"}
+	}
+	if eventName == "" {
+		return 0, ParamError{Param: "eventName"}
+	}
+	if err := c.checkAttributes("data", data); err != nil {
+		return 0, err
+	}
+	_, status, err := c.requestStatus(ctx, "POST",
+		fmt.Sprintf("%s/api/v1/customers/%s/events", c.URL, url.PathEscape(customerID)),
+		map[string]interface{}{
+			"name": eventName,
+			"data": data,
+		})
+	return status, err
+}
+
+// Track sends a single event to Customer.io for the supplied user
+func (c *CustomerIO) Track(customerID string, eventName string, data map[string]interface{}) error {
+	return c.TrackCtx(context.Background(), customerID, eventName, data)
+}
+
+// TrackWithIDCtx sends a single event to Customer.io for the supplied user,
+// with an id that Customer.io uses to discard duplicate copies of the event.
+// Customer.io only deduplicates within a window measured from when it first
+// received an event with that id, not from the event's timestamp, so a
+// backdated event is deduplicated for the same period as any other, and a
+// retry sent after the window has passed is recorded again.
+func (c *CustomerIO) TrackWithIDCtx(ctx context.Context, customerID, eventName, eventID string, data map[string]interface{}) error {
 	if customerID == "" {
 		return ParamError{Param: "customerID"}
 	}
 	if eventName == "" {
 		return ParamError{Param: "eventName"}
 	}
+	if eventID == "" {
+		return ParamError{Param: "eventID"}
+	}
+	if err := c.checkAttributes("data", data); err != nil {
+		return err
+	}
 	_, err := c.request(ctx, "POST",
 		fmt.Sprintf("%s/api/v1/customers/%s/events", c.URL, url.PathEscape(customerID)),
 		map[string]interface{}{
+			"id":   eventID,
 			"name": eventName,
 			"data": data,
 		})
 	return err
 }
 
-// Track sends a single event to Customer.io for the supplied user
-func (c *CustomerIO) Track(customerID string, eventName string, data map[string]interface{}) error {
-	return c.TrackCtx(context.Background(), customerID, eventName, data)
+// TrackWithID sends a single event to Customer.io for the supplied user, with
+// an id used for deduplication
+func (c *CustomerIO) TrackWithID(customerID, eventName, eventID string, data map[string]interface{}) error {
+	return c.TrackWithIDCtx(context.Background(), customerID, eventName, eventID, data)
+}
+
+// ErrNotSupported is returned by methods for operations the Customer.io API
+// doesn't provide.
+var ErrNotSupported = errors.New("not supported by the Customer.io API")
+
+// DeleteEvent would delete an event tracked with TrackWithIDCtx, but
+// Customer.io has no API for deleting or editing tracked events, so it always
+// returns ErrNotSupported. Events are kept until they age out of the
+// workspace's retention period, or until the customer is deleted. To correct
+// reporting, track a compensating event, for example a refund for an order
+// tracked with the wrong amount, and account for it in segments and
+// campaigns.
+func (c *CustomerIO) DeleteEvent(ctx context.Context, customerID, eventID string) error {
+	if customerID == "" {
+		return ParamError{Param: "customerID"}
+	}
+	if eventID == "" {
+		return ParamError{Param: "eventID"}
+	}
+	return ErrNotSupported
 }
 
 // TrackAnonymousCtx sends a single event to Customer.io for the anonymous user
@@ -115,6 +310,9 @@ func (c *CustomerIO) TrackAnonymousCtx(ctx context.Context, anonymousID, eventNa
 	if eventName == "" {
 		return ParamError{Param: "eventName"}
 	}
+	if err := c.checkAttributes("data", data); err != nil {
+		return err
+	}
 
 	payload := map[string]interface{}{
 		"name": eventName,
@@ -129,6 +327,24 @@ func (c *CustomerIO) TrackAnonymousCtx(ctx context.Context, anonymousID, eventNa
 	return err
 }
 
+// IdentifyWithAnonymousIDCtx is like IdentifyCtx, and also claims the events
+// previously sent with TrackAnonymousCtx for anonymousID, merging them into
+// the customer's profile. Customer.io doesn't store attributes for anonymous
+// users, only their events, so attributes known before sign-up should be sent
+// here once the customer is identified. Anonymous events are only kept for a
+// limited time before they expire unclaimed.
+func (c *CustomerIO) IdentifyWithAnonymousIDCtx(ctx context.Context, customerID, anonymousID string, attributes map[string]interface{}) error {
+	if anonymousID == "" {
+		return ParamError{Param: "anonymousID"}
+	}
+	attrs := make(map[string]interface{}, len(attributes)+1)
+	for k, v := range attributes {
+		attrs[k] = v
+	}
+	attrs["anonymous_id"] = anonymousID
+	return c.IdentifyCtx(ctx, customerID, attrs)
+}
+
 // TrackAnonymous sends a single event to Customer.io for the anonymous user
 func (c *CustomerIO) TrackAnonymous(anonymousID, eventName string, data map[string]interface{}) error {
 	return c.TrackAnonymousCtx(context.Background(), anonymousID, eventName, data)
@@ -136,13 +352,20 @@ func (c *CustomerIO) TrackAnonymous(anonymousID, eventName string, data map[stri
 
 // DeleteCtx deletes a customer
 func (c *CustomerIO) DeleteCtx(ctx context.Context, customerID string) error {
+	_, err := c.DeleteWithStatusCtx(ctx, customerID)
+	return err
+}
+
+// DeleteWithStatusCtx is like DeleteCtx but also returns the HTTP status code
+// of the successful response.
+func (c *CustomerIO) DeleteWithStatusCtx(ctx context.Context, customerID string) (int, error) {
 	if customerID == "" {
-		return ParamError{Param: "customerID"}
+		return 0, ParamError{Param: "customerID"}
 	}
-	_, err := c.request(ctx, "DELETE",
+	_, status, err := c.requestStatus(ctx, "DELETE",
 		fmt.Sprintf("%s/api/v1/customers/%s", c.URL, url.PathEscape(customerID)),
 		nil)
-	return err
+	return status, err
 }
 
 // Delete deletes a customer
@@ -161,6 +384,11 @@ func (c *CustomerIO) AddDeviceCtx(ctx context.Context, customerID string, device
 	if platform == "" {
 		return ParamError{Param: "platform"}
 	}
+	if c.strictPlatforms {
+		if err := DevicePlatform(platform).Validate(); err != nil {
+			return err
+		}
+	}
 
 	body := map[string]map[string]interface{}{
 		"device": {
@@ -182,6 +410,34 @@ func (c *CustomerIO) AddDevice(customerID string, deviceID string, platform stri
 	return c.AddDeviceCtx(context.Background(), customerID, deviceID, platform, data)
 }
 
+// DevicePlatform is the platform of a device registered with AddDeviceTyped.
+type DevicePlatform string
+
+const (
+	PlatformIOS     DevicePlatform = "ios"
+	PlatformAndroid DevicePlatform = "android"
+)
+
+// Validate returns a ParamError if the platform is not one Customer.io can
+// deliver push notifications to.
+func (p DevicePlatform) Validate() error {
+	switch p {
+	case PlatformIOS, PlatformAndroid:
+		return nil
+	case "":
+		return ParamError{Param: "platform"}
+	}
+	return ParamError{Param: "platform", Reason: fmt.Sprintf("unsupported platform %q", string(p))}
+}
+
+// AddDeviceTyped adds a device for a customer, rejecting unknown platforms
+func (c *CustomerIO) AddDeviceTyped(ctx context.Context, customerID string, deviceID string, platform DevicePlatform, data map[string]interface{}) error {
+	if err := platform.Validate(); err != nil {
+		return err
+	}
+	return c.AddDeviceCtx(ctx, customerID, deviceID, string(platform), data)
+}
+
 // DeleteDeviceCtx deletes a device for a customer
 func (c *CustomerIO) DeleteDeviceCtx(ctx context.Context, customerID string, deviceID string) error {
 	if customerID == "" {
@@ -201,57 +457,72 @@ func (c *CustomerIO) DeleteDevice(customerID string, deviceID string) error {
 	return c.DeleteDeviceCtx(context.Background(), customerID, deviceID)
 }
 
+// checkAttributes validates attrs if attribute validation is enabled.
+func (c *CustomerIO) checkAttributes(param string, attrs map[string]interface{}) error {
+	if !c.validateAttributes {
+		return nil
+	}
+	return validateAttributes(param, attrs)
+}
+
 func (c *CustomerIO) auth() string {
-	return base64.URLEncoding.EncodeToString([]byte(fmt.Sprintf("%v:%v", c.siteID, c.apiKey)))
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%v:%v", c.siteID, c.apiKey)))
 }
 
 func (c *CustomerIO) request(ctx context.Context, method, url string, body interface{}) ([]byte, error) {
-	var req *http.Request
-	if body != nil {
-		j, err := json.Marshal(body)
-		if err != nil {
-			return nil, err
-		}
-
-		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(j))
-		if err != nil {
-			return nil, err
-		}
-		req = req.WithContext(ctx)
+	responseBody, _, err := c.requestStatus(ctx, method, url, body)
+	return responseBody, err
+}
 
-		req.Header.Add("User-Agent", c.UserAgent)
-		req.Header.Add("Content-Type", "application/json")
-		req.Header.Add("Content-Length", strconv.Itoa(len(j)))
-	} else {
+// requestStatus sends a request to the track API, returning the response body
+// and status code. Any 2xx status is treated as success.
+func (c *CustomerIO) requestStatus(ctx context.Context, method, url string, body interface{}) ([]byte, int, error) {
+	var j []byte
+	if body != nil {
 		var err error
-		req, err = http.NewRequestWithContext(ctx, method, url, nil)
+		j, err = json.Marshal(body)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 	}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Basic %v", c.auth()))
-
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	responseBody, statusCode, err := c.send(ctx, c.Client, trackEndpointClass(url), method, url, len(j), func(ctx context.Context) (*http.Request, error) {
+		var req *http.Request
+		if j != nil {
+			var err error
+			req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(j))
+			if err != nil {
+				return nil, err
+			}
+			req = req.WithContext(ctx)
+
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("Content-Length", strconv.Itoa(len(j)))
+		} else {
+			var err error
+			req, err = http.NewRequestWithContext(ctx, method, url, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	responseBody, err := ioutil.ReadAll(resp.Body)
+		req.Header.Add("User-Agent", c.UserAgent)
+		req.Header.Add("Authorization", fmt.Sprintf("Basic %v", c.auth()))
+		return req, nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, &CustomerIOError{
-			status: resp.StatusCode,
+	if statusCode < 200 || statusCode >= 300 {
+		return nil, statusCode, validationError(&CustomerIOError{
+			status: statusCode,
 			url:    url,
 			body:   responseBody,
-		}
+		})
 	}
 
-	return responseBody, nil
+	return responseBody, statusCode, nil
 }
 
 type IdentifierType string
@@ -271,13 +542,20 @@ type Identifier struct {
 	Value string
 }
 
-func (id Identifier) kv() map[string]string {
+// Map returns the identifier as the single-entry map used in request bodies.
+func (id Identifier) Map() map[string]string {
 	return map[string]string{
 		string(id.Type): id.Value,
 	}
 }
 
-func (id Identifier) validate() error {
+func (id Identifier) kv() map[string]string {
+	return id.Map()
+}
+
+// Validate checks that the identifier identifies a customer: its type must be
+// id, email or cio_id, and its value must not be blank.
+func (id Identifier) Validate() error {
 	if !(id.Type == IdentifierTypeID ||
 		id.Type == IdentifierTypeEmail ||
 		id.Type == IdentifierTypeCioID) {
@@ -291,6 +569,10 @@ func (id Identifier) validate() error {
 	return nil
 }
 
+func (id Identifier) validate() error {
+	return id.Validate()
+}
+
 // MergeCustomersCtx sends a request to Customer.io to merge two customer profiles together.
 func (c *CustomerIO) MergeCustomersCtx(ctx context.Context, primary Identifier, secondary Identifier) error {
 	if primary.validate() != nil {
@@ -309,6 +591,74 @@ func (c *CustomerIO) MergeCustomersCtx(ctx context.Context, primary Identifier,
 	return err
 }
 
+// MergePair is a pair of customer profiles to merge with MergeCustomersBatch.
+type MergePair struct {
+	Primary   Identifier
+	Secondary Identifier
+}
+
+// MergeResult is the outcome of merging a MergePair.
+type MergeResult struct {
+	Pair MergePair
+	Err  error
+}
+
+// mergeBatchConcurrency bounds the merges made concurrently by
+// MergeCustomersBatch.
+const mergeBatchConcurrency = 8
+
+// MergeCustomersBatch merges each pair of customer profiles, with a bounded
+// number of requests in flight. Every pair is validated before any merges are
+// sent, and if any are invalid nothing is merged. Results are aligned with
+// pairs. If any merges fail, or ctx is cancelled before every merge is sent,
+// a *BatchError keyed by pair index is returned alongside the results.
+func (c *CustomerIO) MergeCustomersBatch(ctx context.Context, pairs []MergePair) (results []MergeResult, err error) {
+	results = make([]MergeResult, len(pairs))
+	failures := map[int]error{}
+	for i, pair := range pairs {
+		results[i].Pair = pair
+		if pair.Primary.validate() != nil {
+			failures[i] = ParamError{Param: "primary"}
+		} else if pair.Secondary.validate() != nil {
+			failures[i] = ParamError{Param: "secondary"}
+		}
+		results[i].Err = failures[i]
+	}
+	if len(failures) > 0 {
+		return results, &BatchError{Failures: failures}
+	}
+
+	sem := make(chan struct{}, mergeBatchConcurrency)
+	var wg sync.WaitGroup
+	for i, pair := range pairs {
+		select {
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int, pair MergePair) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i].Err = c.MergeCustomersCtx(ctx, pair.Primary, pair.Secondary)
+		}(i, pair)
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		if r.Err != nil {
+			failures[i] = r.Err
+		}
+	}
+	if len(failures) > 0 {
+		return results, &BatchError{Failures: failures}
+	}
+	return results, nil
+}
+
 type RegionResponse struct {
 	Url           string `json:"url"`
 	Region        string `json:"region"`
@@ -343,7 +693,11 @@ func (c *CustomerIO) AddOrUpdate(ctx context.Context, id string, req *Customer)
 		outgoingAtts[k] = v
 	}
 	if req.CreatedAt != nil {
-		outgoingAtts["created_at"] = req.CreatedAt.UTC().Unix()
+		createdAt, err := epochSeconds("created_at", *req.CreatedAt, c.autoMillis)
+		if err != nil {
+			return err
+		}
+		outgoingAtts["created_at"] = createdAt
 	}
 	if req.Email != "" {
 		outgoingAtts["email"] = req.Email
@@ -354,6 +708,9 @@ func (c *CustomerIO) AddOrUpdate(ctx context.Context, id string, req *Customer)
 	if req.Unsubscribed != nil {
 		outgoingAtts["unsubscribed"] = req.Unsubscribed
 	}
+	if req.Timezone != "" {
+		outgoingAtts["timezone"] = req.Timezone
+	}
 
 	url := fmt.Sprintf("%s/api/v1/customers/%s", c.URL, id)
 	_, err := c.request(ctx, "PUT", url, outgoingAtts)
@@ -369,23 +726,16 @@ func (c *CustomerIO) AddOrUpdate(ctx context.Context, id string, req *Customer)
 // without a value for that identifier will be skipped. The first return value
 // is the number of identities that we attempted to add to the segment.
 func (c *CustomerIO) AddCustomersToSegment(ctx context.Context, segmentID int, customers []Customer, identifier IdentifierType) (int, error) {
-	identifiers := make([]string, 0, len(customers))
-	for _, customer := range customers {
-		switch identifier {
-		case IdentifierTypeID:
-			identifiers = append(identifiers, customer.ID)
-		case IdentifierTypeEmail:
-			identifiers = append(identifiers, customer.Email)
-		case IdentifierTypeCioID:
-			identifiers = append(identifiers, customer.CioID)
-		}
-	}
-
-	_, err := c.request(ctx, http.MethodPost,
-		fmt.Sprintf("%s/api/v1/segments/%d/add_customers?id_type=%s", c.URL, segmentID, identifier),
-		map[string]interface{}{
-			"ids": identifiers,
-		},
-	)
+	identifiers := customerIdentifiers(customers, identifier)
+	err := c.updateSegmentMembership(ctx, "add_customers", segmentID, identifiers, identifier)
 	return len(identifiers), err
 }
+
+// AddCustomersToSegmentDetailed is like AddCustomersToSegment, but also
+// returns the customers that were skipped because they have no value for
+// the identifier type.
+func (c *CustomerIO) AddCustomersToSegmentDetailed(ctx context.Context, segmentID int, customers []Customer, identifier IdentifierType) (added int, skipped []Customer, err error) {
+	have, skipped := PartitionByIdentifier(customers, identifier)
+	added, err = c.AddCustomersToSegment(ctx, segmentID, have, identifier)
+	return added, skipped, err
+}
@@ -2,15 +2,20 @@ package customerio_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/customerio/go-customerio/v3"
@@ -104,6 +109,123 @@ func TestTrack(t *testing.T) {
 		})
 }
 
+func TestTrackWithID(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/api/v1/customers/1/events" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+
+	data := map[string]interface{}{
+		"a": "1",
+	}
+	err := track.TrackWithID("1", "test", "", data)
+	checkParamError(t, err, "eventID")
+
+	if err := track.TrackWithID("1", "test", "01E4C4CT6YDC7Y5M7FE1GWWPQJ", data); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"data": map[string]interface{}{
+			"a": "1",
+		},
+		"id":   "01E4C4CT6YDC7Y5M7FE1GWWPQJ",
+		"name": "test",
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("wrong body. got: %v, want: %v", body, want)
+	}
+}
+
+func TestIdentifyWithAnonymousID(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "PUT" || req.URL.Path != "/api/v1/customers/1" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+
+	attrs := map[string]interface{}{
+		"plan": "pro",
+	}
+	err := track.IdentifyWithAnonymousIDCtx(context.Background(), "1", "", attrs)
+	checkParamError(t, err, "anonymousID")
+
+	if err := track.IdentifyWithAnonymousIDCtx(context.Background(), "1", "anon-1", attrs); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"plan":         "pro",
+		"anonymous_id": "anon-1",
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("wrong body. got: %v, want: %v", body, want)
+	}
+	if want := map[string]interface{}{"plan": "pro"}; !reflect.DeepEqual(attrs, want) {
+		t.Errorf("caller's attributes were modified. got: %v, want: %v", attrs, want)
+	}
+}
+
+func TestCreateCustomer(t *testing.T) {
+	var identified []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "GET" && req.URL.Path == "/v1/customers/taken/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"taken","cio_id":"a"}}}`))
+		case req.Method == "GET" && req.URL.Path == "/v1/customers/new/attributes":
+			w.WriteHeader(http.StatusNotFound)
+		case req.Method == "GET" && req.URL.Path == "/v1/customers/broken/attributes":
+			w.WriteHeader(http.StatusInternalServerError)
+		case req.Method == "PUT" && strings.HasPrefix(req.URL.Path, "/api/v1/customers/"):
+			identified = append(identified, strings.TrimPrefix(req.URL.Path, "/api/v1/customers/"))
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	track := customerio.NewTrackClient("siteid", "apikey", customerio.WithAPIClient(api))
+	track.URL = srv.URL
+	ctx := context.Background()
+	attrs := map[string]interface{}{"plan": "pro"}
+
+	if err := track.CreateCustomerCtx(ctx, "taken", attrs); !errors.Is(err, customerio.ErrCustomerExists) {
+		t.Errorf("wrong error. got: %v, want: %v", err, customerio.ErrCustomerExists)
+	}
+	if err := track.CreateCustomerCtx(ctx, "broken", attrs); err == nil {
+		t.Error("expected an error when the existence check fails")
+	}
+	if err := track.CreateCustomerCtx(ctx, "new", attrs); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"new"}; !reflect.DeepEqual(identified, want) {
+		t.Errorf("wrong identifies. got: %v, want: %v", identified, want)
+	}
+
+	err := track.CreateCustomerCtx(ctx, "", attrs)
+	checkParamError(t, err, "customerID")
+	err = customerio.NewTrackClient("siteid", "apikey").CreateCustomerCtx(ctx, "new", attrs)
+	checkParamError(t, err, "api")
+}
+
 func TestTrackAnonymous(t *testing.T) {
 	data := map[string]interface{}{
 		"a": "1",
@@ -165,6 +287,20 @@ func TestAddDevice(t *testing.T) {
 		})
 }
 
+func TestAddDeviceTyped(t *testing.T) {
+	err := cio.AddDeviceTyped(context.Background(), "1", "d1", "andriod", nil)
+	checkParamError(t, err, "platform")
+
+	strict := customerio.NewTrackClient("siteid", "apikey", customerio.WithStrictDevicePlatforms())
+	err = strict.AddDevice("1", "d1", "andriod", nil)
+	checkParamError(t, err, "platform")
+
+	expect("PUT", "/api/v1/customers/1/devices", nil)
+	if err := cio.AddDeviceTyped(context.Background(), "1", "d1", customerio.PlatformAndroid, nil); err != nil {
+		t.Error(err.Error())
+	}
+}
+
 func TestDeleteDevice(t *testing.T) {
 	err := cio.DeleteDevice("", "d1")
 	checkParamError(t, err, "customerID")
@@ -342,3 +478,279 @@ func TestMergeCustomers(t *testing.T) {
 			}
 		})
 }
+
+func TestValidationError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":[{"field":"plan","reason":"expected string"}]}`))
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	err := client.Identify("1", map[string]interface{}{"plan": 1})
+	var ve customerio.ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected ValidationError, got: %#v", err)
+	}
+	want := []customerio.FieldError{{Field: "plan", Reason: "expected string"}}
+	if !reflect.DeepEqual(ve.Fields, want) {
+		t.Errorf("wrong fields. got: %#v, want: %#v", ve.Fields, want)
+	}
+	var ce *customerio.CustomerIOError
+	if !errors.As(err, &ce) {
+		t.Errorf("expected wrapped CustomerIOError, got: %#v", err)
+	}
+}
+
+func TestIdentifyWithStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	status, err := client.IdentifyWithStatusCtx(context.Background(), "1", map[string]interface{}{"plan": "pro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusCreated {
+		t.Errorf("wrong status. got: %d, want: %d", status, http.StatusCreated)
+	}
+}
+
+func TestClose(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	go func() {
+		<-started
+		client.Close()
+	}()
+	if err := client.Identify("1", nil); !errors.Is(err, customerio.ErrClientClosed) {
+		t.Errorf("wrong error for in-flight request. got: %v, want: %v", err, customerio.ErrClientClosed)
+	}
+	if err := client.Identify("1", nil); !errors.Is(err, customerio.ErrClientClosed) {
+		t.Errorf("wrong error after close. got: %v, want: %v", err, customerio.ErrClientClosed)
+	}
+}
+
+func TestNonJSONError(t *testing.T) {
+	var status int
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	cases := []struct {
+		status      int
+		body        string
+		contentType string
+		message     string
+	}{
+		{http.StatusBadGateway, "<html><body><h1>502 Bad Gateway</h1></body></html>", "text/html", "upstream returned text/html (502)"},
+		{http.StatusServiceUnavailable, "", "", "upstream returned an empty body (503)"},
+		{http.StatusInternalServerError, strings.Repeat("x", 2000), "text/plain", strings.Repeat("x", 512) + "..."},
+		{http.StatusNotFound, `{"meta":{"error":"not found"}}`, "application/json", `{"meta":{"error":"not found"}}`},
+	}
+	for _, c := range cases {
+		status, body = c.status, c.body
+		err := client.Identify("1", nil)
+		var ce *customerio.CustomerIOError
+		if !errors.As(err, &ce) {
+			t.Fatalf("expected CustomerIOError, got: %#v", err)
+		}
+		if got := ce.ContentType(); got != c.contentType {
+			t.Errorf("wrong content type. got: %q, want: %q", got, c.contentType)
+		}
+		if got := err.Error(); !strings.HasSuffix(got, c.message) || len(got) > 700 {
+			t.Errorf("wrong message. got: %q, want suffix: %q", got, c.message)
+		}
+	}
+}
+
+func TestBasicAuthEncoding(t *testing.T) {
+	var user, pass string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var ok bool
+		if user, pass, ok = req.BasicAuth(); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	// This pair encodes to "c2l0ZTprZXk+Pj4=" with standard base64, but
+	// "c2l0ZTprZXk-Pj4=" with the URL-safe alphabet.
+	client := customerio.NewTrackClient("site", "key>>>")
+	client.URL = srv.URL
+
+	if err := client.Identify("1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if user != "site" || pass != "key>>>" {
+		t.Errorf("wrong credentials. got: %s:%s, want: site:key>>>", user, pass)
+	}
+}
+
+func TestAttributeValidation(t *testing.T) {
+	client := customerio.NewTrackClient("siteid", "apikey", customerio.WithAttributeValidation())
+	client.URL = "http://127.0.0.1:0"
+
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+
+	cases := []struct {
+		attrs map[string]interface{}
+		param string
+	}{
+		{map[string]interface{}{"callback": func() {}}, "attributes.callback"},
+		{map[string]interface{}{"address": map[string]interface{}{"updates": make(chan int)}}, "attributes.address.updates"},
+		{map[string]interface{}{"tags": []interface{}{"a", complex(1, 2)}}, "attributes.tags[1]"},
+		{map[string]interface{}{"loop": cyclic}, "attributes.loop.self"},
+		{map[string]interface{}{"address": struct {
+			Zip chan int `json:"zip_code,omitempty"`
+		}{}}, "attributes.address.zip_code"},
+	}
+	for _, c := range cases {
+		err := client.Identify("1", c.attrs)
+		checkParamError(t, err, c.param)
+	}
+
+	for field, attrs := range map[string]map[string]interface{}{
+		"attributes.score":     {"score": math.NaN()},
+		"attributes.limits[0]": {"limits": []float64{math.Inf(1)}},
+		"attributes.stats.ratio": {"stats": struct {
+			Ratio float32 `json:"ratio"`
+		}{float32(math.Inf(-1))}},
+	} {
+		err := client.Identify("1", attrs)
+		var ve customerio.ValidationError
+		if !errors.As(err, &ve) || len(ve.Fields) != 1 || ve.Fields[0].Field != field {
+			t.Errorf("expected a ValidationError for %s, got: %v", field, err)
+		}
+		var cerr *customerio.CustomerIOError
+		if errors.As(err, &cerr) {
+			t.Errorf("local validation error shouldn't wrap a response error: %#v", cerr)
+		}
+	}
+}
+
+func TestRefreshCustomer(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "PUT" || req.URL.Path != "/api/v1/customers/1" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+
+	checkParamError(t, track.RefreshCustomer(context.Background(), ""), "customerID")
+	if err := track.RefreshCustomer(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 0 {
+		t.Errorf("refresh changed attributes: %v", body)
+	}
+}
+
+func TestMergeCustomersBatch(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		merged  []string
+		release = make(chan struct{})
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			Primary   map[string]string `json:"primary"`
+			Secondary map[string]string `json:"secondary"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		secondary := body.Secondary["id"]
+		if secondary == "s0" {
+			// Hold the first merge until the others finish, so results
+			// can only line up with pairs if they are stored by index.
+			<-release
+		}
+		mu.Lock()
+		merged = append(merged, body.Primary["id"]+"<"+secondary)
+		if len(merged) == 2 {
+			close(release)
+		}
+		mu.Unlock()
+		if secondary == "s1" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+	ctx := context.Background()
+
+	id := func(v string) customerio.Identifier {
+		return customerio.Identifier{Type: customerio.IdentifierTypeID, Value: v}
+	}
+
+	pairs := []customerio.MergePair{
+		{Primary: id("p0"), Secondary: id("s0")},
+		{Primary: id("p1"), Secondary: id("s1")},
+		{Primary: id("p2"), Secondary: id("s2")},
+	}
+	results, err := track.MergeCustomersBatch(ctx, pairs)
+	var be *customerio.BatchError
+	if !errors.As(err, &be) || len(be.Failures) != 1 || be.Failures[1] == nil {
+		t.Fatalf("expected a failure for pair 1 only, got: %v", err)
+	}
+	if len(results) != len(pairs) {
+		t.Fatalf("wrong number of results. got: %d, want: %d", len(results), len(pairs))
+	}
+	for i, r := range results {
+		if r.Pair != pairs[i] {
+			t.Errorf("result %d is for the wrong pair: %v", i, r.Pair)
+		}
+		if (r.Err != nil) != (i == 1) {
+			t.Errorf("wrong error for pair %d: %v", i, r.Err)
+		}
+	}
+	if len(merged) != 3 || merged[2] != "p0<s0" {
+		t.Errorf("wrong merges. got: %v", merged)
+	}
+
+	merged = nil
+	invalid := append(pairs[:1:1], customerio.MergePair{Primary: id("p3"), Secondary: id(" ")})
+	results, err = track.MergeCustomersBatch(ctx, invalid)
+	if !errors.As(err, &be) || len(be.Failures) != 1 {
+		t.Fatalf("expected a failure for the invalid pair, got: %v", err)
+	}
+	checkParamError(t, be.Failures[1], "secondary")
+	if results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("wrong results for invalid batch: %v", results)
+	}
+	if len(merged) != 0 {
+		t.Errorf("merges were sent despite an invalid pair: %v", merged)
+	}
+}
@@ -0,0 +1,35 @@
+package customerio
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// inAppMetrics are the metrics that can be reported for an in-app message
+// with TrackInAppMetric.
+var inAppMetrics = []string{"opened", "clicked", "converted", "dismissed"}
+
+// TrackInAppMetric reports a metric for an in-app message delivered to a
+// customer, for messages displayed by the app's own code rather than a
+// Customer.io SDK. metric is one of "opened", "clicked", "converted" or
+// "dismissed". The metric is sent as a delivery event with the v2 batch API,
+// with customerID as its recipient.
+func (c *CustomerIO) TrackInAppMetric(ctx context.Context, customerID, deliveryID, metric string) error {
+	if customerID == "" {
+		return ParamError{Param: "customerID"}
+	}
+	if deliveryID == "" {
+		return ParamError{Param: "deliveryID"}
+	}
+	if !slices.Contains(inAppMetrics, metric) {
+		return ParamError{Param: "metric", Reason: fmt.Sprintf("unrecognized in-app metric %q", metric)}
+	}
+	return c.TrackWriteBatch(ctx, []map[string]any{{
+		"type":        "delivery",
+		"action":      "event",
+		"identifiers": map[string]string{"id": deliveryID},
+		"name":        metric,
+		"attributes":  map[string]any{"recipient": customerID},
+	}})
+}
@@ -0,0 +1,56 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestTrackInAppMetric(t *testing.T) {
+	var body map[string]interface{}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.Method != "POST" || req.URL.Path != "/api/v2/batch" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+
+	if err := track.TrackInAppMetric(context.Background(), "1", "dlv_1", "clicked"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"batch": []interface{}{map[string]interface{}{
+			"type":        "delivery",
+			"action":      "event",
+			"identifiers": map[string]interface{}{"id": "dlv_1"},
+			"name":        "clicked",
+			"attributes":  map[string]interface{}{"recipient": "1"},
+		}},
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("wrong body. got: %v, want: %v", body, want)
+	}
+
+	err := track.TrackInAppMetric(context.Background(), "1", "dlv_1", "delivered")
+	checkParamError(t, err, "metric")
+	err = track.TrackInAppMetric(context.Background(), "1", "", "clicked")
+	checkParamError(t, err, "deliveryID")
+	err = track.TrackInAppMetric(context.Background(), "", "dlv_1", "clicked")
+	checkParamError(t, err, "customerID")
+	if requests != 1 {
+		t.Errorf("wrong number of requests. got: %d, want: 1", requests)
+	}
+}
@@ -0,0 +1,258 @@
+package customerio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrWaitTimeout is returned by the WaitFor functions when WaitOptions.MaxWait
+// elapses before the job finishes.
+var ErrWaitTimeout = errors.New("timed out waiting for job")
+
+// ErrJobFailed is returned by the WaitFor functions, along with the job's
+// final state, when the job finishes without succeeding.
+var ErrJobFailed = errors.New("job failed")
+
+// jobError describes a job of the given kind that finished in state, with
+// the reason reported for it, if any.
+func jobError(kind string, id int, state, reason string) error {
+	if reason != "" {
+		return fmt.Errorf("%s %d %s: %s: %w", kind, id, state, reason, ErrJobFailed)
+	}
+	return fmt.Errorf("%s %d %s: %w", kind, id, state, ErrJobFailed)
+}
+
+// WaitOptions controls how the WaitFor functions poll.
+type WaitOptions struct {
+	// Interval is the delay before the first poll. Defaults to 1 second.
+	Interval time.Duration
+	// MaxInterval caps the delay between polls. Defaults to 30 seconds.
+	MaxInterval time.Duration
+	// Backoff multiplies the delay after each poll. Defaults to 2; values
+	// below 1 are treated as 1.
+	Backoff float64
+	// MaxWait, if set, is the longest to wait before giving up with
+	// ErrWaitTimeout. The context's deadline applies regardless.
+	MaxWait time.Duration
+}
+
+// wait calls poll with increasing delays until it reports the job is done,
+// returns an error, or the wait times out.
+func (o WaitOptions) wait(ctx context.Context, poll func() (bool, error)) error {
+	interval := o.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	maxInterval := o.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = 30 * time.Second
+	}
+	backoff := o.Backoff
+	if backoff == 0 {
+		backoff = 2
+	} else if backoff < 1 {
+		backoff = 1
+	}
+
+	var deadline <-chan time.Time
+	if o.MaxWait > 0 {
+		timer := time.NewTimer(o.MaxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		done, err := poll()
+		if err != nil || done {
+			return err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-deadline:
+			timer.Stop()
+			return ErrWaitTimeout
+		case <-timer.C:
+		}
+		interval = min(time.Duration(float64(interval)*backoff), maxInterval)
+	}
+}
+
+// Export is an export of customer or delivery data.
+type Export struct {
+	ID          int        `json:"id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	Failed      bool       `json:"failed"`
+	Total       int        `json:"total"`
+	Downloads   int        `json:"downloads"`
+	Description string     `json:"description"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+func (e *Export) UnmarshalJSON(b []byte) error {
+	type export Export
+	var r struct {
+		export
+		CreatedAt json.RawMessage `json:"created_at"`
+		UpdatedAt json.RawMessage `json:"updated_at"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	createdAt, err := parseCustomerIOTime(r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	updatedAt, err := parseCustomerIOTime(r.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	*e = Export(r.export)
+	e.CreatedAt = createdAt
+	e.UpdatedAt = updatedAt
+	return nil
+}
+
+// Done reports whether the export has finished, successfully or not.
+func (e Export) Done() bool {
+	return e.Failed || e.Status == "done" || e.Status == "failed"
+}
+
+// GetExport returns the current state of an export.
+func (c *APIClient) GetExport(ctx context.Context, id int) (Export, error) {
+	if id <= 0 {
+		return Export{}, ParamError{Param: "exportID"}
+	}
+	var envelope struct {
+		Export Export `json:"export"`
+	}
+	if err := c.getJob(ctx, fmt.Sprintf("/v1/exports/%d", id), &envelope); err != nil {
+		return Export{}, err
+	}
+	return envelope.Export, nil
+}
+
+// WaitForExport polls the export until it is done, returning its final state.
+// If the export failed, the error wraps ErrJobFailed.
+func WaitForExport(ctx context.Context, c *APIClient, id int, poll WaitOptions) (Export, error) {
+	var export Export
+	err := poll.wait(ctx, func() (bool, error) {
+		var err error
+		export, err = c.GetExport(ctx, id)
+		return export.Done(), err
+	})
+	if err == nil && (export.Failed || export.Status == "failed") {
+		err = jobError("export", id, "failed", "")
+	}
+	return export, err
+}
+
+// Import is a CSV import of people, objects or events.
+type Import struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	State         string `json:"state"`
+	RowsToProcess int    `json:"rows_to_process"`
+	RowsProcessed int    `json:"rows_processed"`
+	Error         string `json:"error"`
+}
+
+// Done reports whether the import has finished, successfully or not.
+func (i Import) Done() bool {
+	return i.State == "completed" || i.State == "failed" || i.State == "canceled"
+}
+
+// GetImport returns the current state of an import.
+func (c *APIClient) GetImport(ctx context.Context, id int) (Import, error) {
+	if id <= 0 {
+		return Import{}, ParamError{Param: "importID"}
+	}
+	var envelope struct {
+		Import Import `json:"import"`
+	}
+	if err := c.getJob(ctx, fmt.Sprintf("/v1/imports/%d", id), &envelope); err != nil {
+		return Import{}, err
+	}
+	return envelope.Import, nil
+}
+
+// WaitForImport polls the import until it is done, returning its final state.
+// If the import failed or was canceled, the error wraps ErrJobFailed.
+func WaitForImport(ctx context.Context, c *APIClient, id int, poll WaitOptions) (Import, error) {
+	var imp Import
+	err := poll.wait(ctx, func() (bool, error) {
+		var err error
+		imp, err = c.GetImport(ctx, id)
+		return imp.Done(), err
+	})
+	if err == nil && (imp.State == "failed" || imp.State == "canceled") {
+		err = jobError("import", id, imp.State, imp.Error)
+	}
+	return imp, err
+}
+
+// BroadcastTrigger is a single trigger of an API-triggered broadcast.
+type BroadcastTrigger struct {
+	ID        int  `json:"id"`
+	Processed bool `json:"processed"`
+	Failed    bool `json:"failed"`
+}
+
+// Done reports whether the trigger has been processed, successfully or not.
+func (t BroadcastTrigger) Done() bool {
+	return t.Processed || t.Failed
+}
+
+// GetBroadcastTrigger returns the current state of a broadcast trigger.
+func (c *APIClient) GetBroadcastTrigger(ctx context.Context, broadcastID, triggerID int) (BroadcastTrigger, error) {
+	if broadcastID <= 0 {
+		return BroadcastTrigger{}, ParamError{Param: "broadcastID"}
+	}
+	if triggerID <= 0 {
+		return BroadcastTrigger{}, ParamError{Param: "triggerID"}
+	}
+	var envelope struct {
+		Trigger BroadcastTrigger `json:"trigger"`
+	}
+	if err := c.getJob(ctx, fmt.Sprintf("/v1/campaigns/%d/triggers/%d", broadcastID, triggerID), &envelope); err != nil {
+		return BroadcastTrigger{}, err
+	}
+	return envelope.Trigger, nil
+}
+
+// WaitForBroadcastTrigger polls the broadcast trigger until it has been
+// processed, returning its final state. If the trigger failed, the error
+// wraps ErrJobFailed.
+func WaitForBroadcastTrigger(ctx context.Context, c *APIClient, broadcastID, triggerID int, poll WaitOptions) (BroadcastTrigger, error) {
+	var trigger BroadcastTrigger
+	err := poll.wait(ctx, func() (bool, error) {
+		var err error
+		trigger, err = c.GetBroadcastTrigger(ctx, broadcastID, triggerID)
+		return trigger.Done(), err
+	})
+	if err == nil && trigger.Failed {
+		err = jobError("broadcast trigger", triggerID, "failed", "")
+	}
+	return trigger, err
+}
+
+func (c *APIClient) getJob(ctx context.Context, url string, v interface{}) error {
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,137 @@
+package customerio_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestWaitForExport(t *testing.T) {
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/exports/3" {
+			t.Errorf("unexpected path: %s", req.URL.Path)
+		}
+		polls++
+		status := "pending"
+		if polls == 3 {
+			status = "done"
+		}
+		fmt.Fprintf(w, `{"export":{"id":3,"status":%q,"created_at":1500111111}}`, status)
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	export, err := customerio.WaitForExport(context.Background(), api, 3, customerio.WaitOptions{
+		Interval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 3 || export.Status != "done" {
+		t.Errorf("wrong result after %d polls: %#v", polls, export)
+	}
+
+	polls = -100
+	_, err = customerio.WaitForExport(context.Background(), api, 3, customerio.WaitOptions{
+		Interval: time.Millisecond,
+		MaxWait:  20 * time.Millisecond,
+	})
+	if err != customerio.ErrWaitTimeout {
+		t.Errorf("expected ErrWaitTimeout, got: %v", err)
+	}
+}
+
+func TestWaitForExportFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"export":{"id":3,"status":"failed","failed":true}}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	export, err := customerio.WaitForExport(context.Background(), api, 3, customerio.WaitOptions{Interval: time.Millisecond})
+	if !errors.Is(err, customerio.ErrJobFailed) {
+		t.Errorf("expected ErrJobFailed, got: %v", err)
+	}
+	if export.ID != 3 || !export.Failed {
+		t.Errorf("wrong final state: %#v", export)
+	}
+}
+
+func TestWaitForImport(t *testing.T) {
+	states := map[string][]string{
+		"/v1/imports/1": {"processing", "completed"},
+		"/v1/imports/2": {"processing", "canceled"},
+	}
+	polls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		seq, ok := states[req.URL.Path]
+		if !ok {
+			t.Errorf("unexpected path: %s", req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		state := seq[min(polls[req.URL.Path], len(seq)-1)]
+		polls[req.URL.Path]++
+		fmt.Fprintf(w, `{"import":{"id":1,"state":%q,"rows_processed":10,"error":"stopped by user"}}`, state)
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	poll := customerio.WaitOptions{Interval: time.Millisecond}
+
+	imp, err := customerio.WaitForImport(context.Background(), api, 1, poll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.State != "completed" || imp.RowsProcessed != 10 || polls["/v1/imports/1"] != 2 {
+		t.Errorf("wrong result after %d polls: %#v", polls["/v1/imports/1"], imp)
+	}
+
+	imp, err = customerio.WaitForImport(context.Background(), api, 2, poll)
+	if !errors.Is(err, customerio.ErrJobFailed) {
+		t.Errorf("expected ErrJobFailed, got: %v", err)
+	}
+	if imp.State != "canceled" {
+		t.Errorf("wrong final state: %#v", imp)
+	}
+}
+
+func TestWaitForBroadcastTrigger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/campaigns/5/triggers/1":
+			w.Write([]byte(`{"trigger":{"id":1,"processed":true}}`))
+		case "/v1/campaigns/5/triggers/2":
+			w.Write([]byte(`{"trigger":{"id":2,"failed":true}}`))
+		default:
+			t.Errorf("unexpected path: %s", req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	poll := customerio.WaitOptions{Interval: time.Millisecond}
+
+	trigger, err := customerio.WaitForBroadcastTrigger(context.Background(), api, 5, 1, poll)
+	if err != nil || !trigger.Processed {
+		t.Errorf("expected processed trigger, got: %#v, %v", trigger, err)
+	}
+	trigger, err = customerio.WaitForBroadcastTrigger(context.Background(), api, 5, 2, poll)
+	if !errors.Is(err, customerio.ErrJobFailed) || !trigger.Failed {
+		t.Errorf("expected failed trigger with ErrJobFailed, got: %#v, %v", trigger, err)
+	}
+}
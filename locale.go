@@ -0,0 +1,98 @@
+package customerio
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// IdentifyWithLocaleCtx is like IdentifyCtx, also setting the customer's
+// timezone and locale attributes, which Customer.io uses for timezone-aware
+// sends and localized messages. tz must be a named IANA location, as returned
+// by time.LoadLocation, rather than time.Local or a time.FixedZone. locale
+// must be a BCP 47 language tag such as "en-US"; it is sent with canonical
+// casing. Either may be left unset with nil or an empty string. They take
+// precedence over timezone and locale keys in attributes.
+//
+// locale is a string rather than a golang.org/x/text/language.Tag so that
+// this package keeps having no dependencies outside the standard library;
+// pass tag.String() to use a language.Tag.
+func (c *CustomerIO) IdentifyWithLocaleCtx(ctx context.Context, customerID string, tz *time.Location, locale string, attributes map[string]interface{}) error {
+	attrs := make(map[string]interface{}, len(attributes)+2)
+	for k, v := range attributes {
+		attrs[k] = v
+	}
+	if tz != nil {
+		name := tz.String()
+		if _, err := time.LoadLocation(name); err != nil || name == "Local" || name == "" {
+			return ParamError{Param: "timezone", Reason: "not a named IANA location: " + name}
+		}
+		attrs["timezone"] = name
+	}
+	if locale != "" {
+		tag, err := canonicalLanguageTag(locale)
+		if err != nil {
+			return err
+		}
+		attrs["locale"] = tag
+	}
+	return c.IdentifyCtx(ctx, customerID, attrs)
+}
+
+// canonicalLanguageTag checks that tag is well-formed BCP 47, returning it
+// with the conventional casing of each subtag, e.g. "en-us" becomes "en-US".
+// It checks the tag's syntax, not whether its subtags are registered, and
+// only accepts the two and three letter primary language subtags in use.
+func canonicalLanguageTag(tag string) (string, error) {
+	invalid := ParamError{Param: "locale", Reason: "not a BCP 47 language tag: " + tag}
+
+	subtags := strings.Split(tag, "-")
+	lang := subtags[0]
+	if len(lang) < 2 || len(lang) > 3 || !isAlpha(lang) {
+		return "", invalid
+	}
+	subtags[0] = strings.ToLower(lang)
+
+	for i := 1; i < len(subtags); i++ {
+		s := subtags[i]
+		if len(s) == 0 || len(s) > 8 || !isAlphanumeric(s) {
+			return "", invalid
+		}
+		switch {
+		case len(s) == 1:
+			// A singleton introduces an extension or private use
+			// sequence, which is left as is.
+			for _, rest := range subtags[i+1:] {
+				if len(rest) == 0 || len(rest) > 8 || !isAlphanumeric(rest) {
+					return "", invalid
+				}
+			}
+			return strings.Join(subtags, "-"), nil
+		case i == 1 && len(s) == 4 && isAlpha(s):
+			subtags[i] = strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+		case len(s) == 2 && isAlpha(s):
+			subtags[i] = strings.ToUpper(s)
+		default:
+			subtags[i] = strings.ToLower(s)
+		}
+	}
+	return strings.Join(subtags, "-"), nil
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && !isAlpha(string(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package customerio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCanonicalLanguageTag(t *testing.T) {
+	cases := []struct {
+		tag  string
+		want string
+	}{
+		{"en", "en"},
+		{"en-us", "en-US"},
+		{"EN-GB", "en-GB"},
+		{"zh-hant-tw", "zh-Hant-TW"},
+		{"es-419", "es-419"},
+		{"de-CH-x-Phonebk", "de-CH-x-Phonebk"},
+		{"en_us", ""},
+		{"e", ""},
+		{"en-", ""},
+		{"america", ""},
+	}
+	for _, c := range cases {
+		got, err := canonicalLanguageTag(c.tag)
+		if c.want == "" {
+			if err == nil {
+				t.Errorf("%s: expected error, got: %s", c.tag, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.tag, err)
+		} else if got != c.want {
+			t.Errorf("wrong tag for %s. got: %s, want: %s", c.tag, got, c.want)
+		}
+	}
+}
+
+func TestIdentifyWithLocale(t *testing.T) {
+	var body map[string]interface{}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	track := NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+	ctx := context.Background()
+
+	tz, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	attrs := map[string]interface{}{"timezone": "UTC", "locale": "fr", "plan": "pro"}
+	if err := track.IdentifyWithLocaleCtx(ctx, "1", tz, "en-us", attrs); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"timezone": "America/New_York", "locale": "en-US", "plan": "pro"}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("wrong body. got: %v, want: %v", body, want)
+	}
+
+	invalid := []struct {
+		tz     *time.Location
+		locale string
+		param  string
+	}{
+		{time.Local, "", "timezone"},
+		{time.FixedZone("Foo", 3600), "", "timezone"},
+		{time.FixedZone("", 0), "", "timezone"},
+		{nil, "en_US", "locale"},
+		{nil, "english", "locale"},
+	}
+	for _, c := range invalid {
+		err := track.IdentifyWithLocaleCtx(ctx, "1", c.tz, c.locale, nil)
+		var pe ParamError
+		if !errors.As(err, &pe) || pe.Param != c.param {
+			t.Errorf("%v %q: expected a ParamError for %s, got: %v", c.tz, c.locale, c.param, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("wrong number of requests. got: %d, want: 1", requests)
+	}
+}
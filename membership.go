@@ -0,0 +1,156 @@
+package customerio
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+)
+
+// MembershipSet is a snapshot of a segment's membership for fast local
+// checks. It reflects the membership when it was loaded and is not updated
+// as customers enter or leave the segment, so callers should reload it as
+// often as their tolerance for staleness requires. It is safe for concurrent
+// use.
+type MembershipSet struct {
+	// LoadedAt is when the membership was read.
+	LoadedAt time.Time
+
+	idType IdentifierType
+	ids    map[string]struct{}
+	bloom  *bloomFilter
+	n      int
+}
+
+// Contains reports whether the customer with the identifier was in the
+// segment. A set loaded with LoadSegmentMembershipFilter may report false
+// positives, but never false negatives.
+func (s *MembershipSet) Contains(id string) bool {
+	id = s.normalize(id)
+	if s.bloom != nil {
+		return s.bloom.contains(id)
+	}
+	_, ok := s.ids[id]
+	return ok
+}
+
+// Len returns the number of members in the set.
+func (s *MembershipSet) Len() int {
+	return s.n
+}
+
+func (s *MembershipSet) normalize(id string) string {
+	if s.idType == IdentifierTypeEmail {
+		return strings.ToLower(id)
+	}
+	return id
+}
+
+// LoadSegmentMembershipSet reads the full membership of a segment into a
+// MembershipSet keyed by idType, for checking membership without a request
+// per check. Members without a value for idType are left out, and emails are
+// compared case-insensitively.
+func (c *APIClient) LoadSegmentMembershipSet(ctx context.Context, segmentID int, idType IdentifierType) (*MembershipSet, error) {
+	set, ids, err := c.loadMembership(ctx, segmentID, idType)
+	if err != nil {
+		return nil, err
+	}
+	set.ids = make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set.ids[id] = struct{}{}
+	}
+	set.n = len(set.ids)
+	return set, nil
+}
+
+// LoadSegmentMembershipFilter is like LoadSegmentMembershipSet, but backs the
+// set with a bloom filter, which uses far less memory for large segments at
+// the cost of Contains reporting false positives at about falsePositiveRate,
+// which must be between 0 and 1. The full membership is still held in
+// memory while it is loaded.
+func (c *APIClient) LoadSegmentMembershipFilter(ctx context.Context, segmentID int, idType IdentifierType, falsePositiveRate float64) (*MembershipSet, error) {
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, ParamError{Param: "falsePositiveRate", Reason: "must be between 0 and 1"}
+	}
+	set, ids, err := c.loadMembership(ctx, segmentID, idType)
+	if err != nil {
+		return nil, err
+	}
+	set.bloom = newBloomFilter(len(ids), falsePositiveRate)
+	for _, id := range ids {
+		set.bloom.add(id)
+	}
+	set.n = len(ids)
+	return set, nil
+}
+
+// loadMembership reads the normalized, de-duplicated identifiers of a
+// segment's members.
+func (c *APIClient) loadMembership(ctx context.Context, segmentID int, idType IdentifierType) (*MembershipSet, []string, error) {
+	set := &MembershipSet{LoadedAt: time.Now(), idType: idType}
+	members, err := c.GetSegmentMembership(ctx, segmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool, len(members))
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		id := set.normalize(m.identifier(idType))
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return set, ids, nil
+}
+
+// bloomFilter is a fixed-size bloom filter over strings.
+type bloomFilter struct {
+	bits   []uint64
+	m      uint64
+	hashes uint64
+}
+
+// newBloomFilter sizes a filter for n items with the given false positive
+// rate.
+func newBloomFilter(n int, falsePositiveRate float64) *bloomFilter {
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	hashes := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &bloomFilter{
+		bits:   make([]uint64, (m+63)/64),
+		m:      m,
+		hashes: max(hashes, 1),
+	}
+}
+
+// locations derives the filter's bit positions for s from two halves of a
+// single 64-bit hash, using double hashing.
+func (f *bloomFilter) locations(s string, fn func(bit uint64)) {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	for i := uint64(0); i < f.hashes; i++ {
+		fn((h1 + i*h2) % f.m)
+	}
+}
+
+func (f *bloomFilter) add(s string) {
+	f.locations(s, func(bit uint64) {
+		f.bits[bit/64] |= 1 << (bit % 64)
+	})
+}
+
+func (f *bloomFilter) contains(s string) bool {
+	found := true
+	f.locations(s, func(bit uint64) {
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			found = false
+		}
+	})
+	return found
+}
@@ -0,0 +1,96 @@
+package customerio
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RequestMetrics describes a completed request, for the hook set with
+// WithRequestObserver.
+type RequestMetrics struct {
+	Method string
+	URL    string
+	// StatusCode is zero if no response was received.
+	StatusCode int
+	// Duration includes time spent waiting on rate limits and retries.
+	Duration      time.Duration
+	RequestBytes  int
+	ResponseBytes int
+	// Tag is the tag attached to the request's context with WithRequestTag.
+	Tag string
+	// Err is the error that prevented a response from being read, if any.
+	Err error
+}
+
+type requestTagKey struct{}
+
+// WithRequestTag returns a copy of ctx carrying tag, which is reported in the
+// RequestMetrics of requests made with the context. The tag is never sent to
+// Customer.io.
+func WithRequestTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, requestTagKey{}, tag)
+}
+
+// RequestTag returns the tag attached to ctx with WithRequestTag.
+func RequestTag(ctx context.Context) (string, bool) {
+	tag, ok := ctx.Value(requestTagKey{}).(string)
+	return tag, ok
+}
+
+func (t *transportConfig) observe(ctx context.Context, m RequestMetrics) {
+	if t.observer == nil {
+		return
+	}
+	m.Tag, _ = RequestTag(ctx)
+	t.observer(ctx, m)
+}
+
+// Metrics receives request instrumentation from a client, for bridging to a
+// metrics system such as Prometheus. Set it with WithMetrics. Its methods are
+// called synchronously from every request, so they should not block.
+type Metrics interface {
+	// ObserveRequest records a completed request to an endpoint of the given
+	// class. status is zero if no response was received, and d includes time
+	// spent waiting on rate limits and retries.
+	ObserveRequest(endpointClass string, status int, d time.Duration)
+	// IncError counts a failed request. kind is "timeout", "canceled" or
+	// "network" if no response was received, or "client_error" or
+	// "server_error" for 4xx and 5xx responses. It is also called with
+	// "retry_budget_exhausted" when a retry is refused by the budget set with
+	// WithRetryBudget.
+	IncError(endpointClass, kind string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(string, int, time.Duration) {}
+func (nopMetrics) IncError(string, string)                   {}
+
+// errorKind classifies a failed request for Metrics.IncError. It returns an
+// empty string if the request succeeded.
+func errorKind(status int, err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case err != nil:
+		return "network"
+	case status >= 500:
+		return "server_error"
+	case status >= 400:
+		return "client_error"
+	}
+	return ""
+}
+
+func (t *transportConfig) record(class EndpointClass, status int, d time.Duration, err error) {
+	if t.metrics == nil {
+		return
+	}
+	t.metrics.ObserveRequest(string(class), status, d)
+	if kind := errorKind(status, err); kind != "" {
+		t.metrics.IncError(string(class), kind)
+	}
+}
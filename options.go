@@ -1,6 +1,10 @@
 package customerio
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"time"
+)
 
 type option struct {
 	api   func(*APIClient)
@@ -55,3 +59,280 @@ func WithUserAgent(ua string) option {
 		},
 	}
 }
+
+// WithAPIClient sets the App API client used by the track client's methods
+// that need to read data, such as CreateOrUpdateObject in replace mode. It
+// has no effect on the App API client. When each client needs the other,
+// set the CustomerIO.API or APIClient.Track field of the one created first.
+func WithAPIClient(api *APIClient) option {
+	return option{
+		api: func(a *APIClient) {},
+		track: func(c *CustomerIO) {
+			c.API = api
+		},
+	}
+}
+
+// WithTrackClient sets the track client used by the App API client's methods
+// that change data only writable through the Track API, such as
+// SyncSegmentMembership. It has no effect on the track client.
+func WithTrackClient(track *CustomerIO) option {
+	return option{
+		api: func(a *APIClient) {
+			a.Track = track
+		},
+		track: func(c *CustomerIO) {},
+	}
+}
+
+// WithRetries enables retrying requests that fail with a retryable status
+// code, up to maxRetries additional attempts, backing off exponentially
+// between attempts. Only idempotent requests are retried; POST requests are
+// always sent once.
+func WithRetries(maxRetries int) option {
+	return option{
+		api: func(a *APIClient) {
+			a.retry.maxRetries = maxRetries
+		},
+		track: func(c *CustomerIO) {
+			c.retry.maxRetries = maxRetries
+		},
+	}
+}
+
+// WithRetryBudget limits the retries made by the client across all of its
+// requests, to stop retries from amplifying the load on Customer.io while it
+// is degraded. Over a sliding ten second window, the client makes at most
+// minPerSec retries per second plus ratio retries for each successful
+// request; once that is used up, failed requests are returned without being
+// retried. Each client has its own budget. The budget has no effect unless
+// retries are enabled with WithRetries. Refused retries are reported to the
+// Metrics set with WithMetrics.
+func WithRetryBudget(ratio float64, minPerSec int) option {
+	return option{
+		api: func(a *APIClient) {
+			a.retry.budget = &retryBudget{ratio: ratio, minPerSec: minPerSec}
+		},
+		track: func(c *CustomerIO) {
+			c.retry.budget = &retryBudget{ratio: ratio, minPerSec: minPerSec}
+		},
+	}
+}
+
+// WithRetryableStatusCodes replaces the set of response status codes that are
+// retried, which defaults to DefaultRetryableStatusCodes. To retry additional
+// codes, include the defaults:
+//
+//	WithRetryableStatusCodes(append(DefaultRetryableStatusCodes, 520, 522)...)
+//
+// The status codes have no effect unless retries are enabled with
+// WithRetries, and do not change which methods are retried: POST requests are
+// never retried since they are not idempotent.
+func WithRetryableStatusCodes(codes ...int) option {
+	return option{
+		api: func(a *APIClient) {
+			a.retry.setStatusCodes(codes)
+		},
+		track: func(c *CustomerIO) {
+			c.retry.setStatusCodes(codes)
+		},
+	}
+}
+
+// WithEndpointTimeout sets a default timeout for requests to endpoints of the
+// given class. The timeout applies on top of the caller's context, so the
+// effective deadline is whichever of the two is sooner.
+func WithEndpointTimeout(class EndpointClass, d time.Duration) option {
+	return option{
+		api: func(a *APIClient) {
+			if a.timeouts == nil {
+				a.timeouts = map[EndpointClass]time.Duration{}
+			}
+			a.timeouts[class] = d
+		},
+		track: func(c *CustomerIO) {
+			if c.timeouts == nil {
+				c.timeouts = map[EndpointClass]time.Duration{}
+			}
+			c.timeouts[class] = d
+		},
+	}
+}
+
+// WithMaxCallDuration bounds every request made with a context that has no
+// deadline to d, including those made by the methods that don't take a
+// context, so that no request can hang indefinitely. Requests whose context
+// already has a deadline are unaffected. Methods that make several requests
+// apply d to each of them.
+func WithMaxCallDuration(d time.Duration) option {
+	return option{
+		api: func(a *APIClient) {
+			a.maxCallDuration = d
+		},
+		track: func(c *CustomerIO) {
+			c.maxCallDuration = d
+		},
+	}
+}
+
+// WithConnectionPool configures the connection pool of the client's internal
+// transport. Zero values keep the defaults. The settings are ignored if a
+// custom http.Client is supplied with WithHTTPClient, since its transport is
+// the caller's to configure.
+func WithConnectionPool(maxIdleConnsPerHost, maxConnsPerHost int, idleTimeout time.Duration) option {
+	pool := &connectionPool{
+		maxIdleConnsPerHost: maxIdleConnsPerHost,
+		maxConnsPerHost:     maxConnsPerHost,
+		idleTimeout:         idleTimeout,
+	}
+	return option{
+		api: func(a *APIClient) {
+			a.pool = pool
+		},
+		track: func(c *CustomerIO) {
+			c.pool = pool
+		},
+	}
+}
+
+// WithAutoMillisecondConversion makes the track client treat timestamps after
+// the year 3000 as having been built from milliseconds, converting them to
+// seconds instead of rejecting them with a ParamError. It has no effect on
+// the App API client.
+func WithAutoMillisecondConversion() option {
+	return option{
+		api: func(a *APIClient) {},
+		track: func(c *CustomerIO) {
+			c.autoMillis = true
+		},
+	}
+}
+
+// WithStrictDevicePlatforms makes the track client's AddDevice methods reject
+// platforms other than PlatformIOS and PlatformAndroid, as AddDeviceTyped
+// always does. It has no effect on the App API client.
+func WithStrictDevicePlatforms() option {
+	return option{
+		api: func(a *APIClient) {},
+		track: func(c *CustomerIO) {
+			c.strictPlatforms = true
+		},
+	}
+}
+
+// WithBatchValidation makes the track client check the actions passed to
+// TrackWriteBatch before sending them, rejecting actions with an unknown type
+// or action, or without identifiers. Validation is off by default so that
+// actions added to the API after this library was written can still be sent.
+// It has no effect on the App API client.
+func WithBatchValidation() option {
+	return option{
+		api: func(a *APIClient) {},
+		track: func(c *CustomerIO) {
+			c.validateBatches = true
+		},
+	}
+}
+
+// WithAttributeValidation makes the track client check that the attributes
+// and event data passed to its Identify and Track methods can be encoded as
+// JSON before sending them, returning a ParamError that names the offending
+// attribute and its Go type, rather than the encoder's error. Channels,
+// functions, complex numbers and cyclic values are rejected. It has no
+// effect on the App API client.
+func WithAttributeValidation() option {
+	return option{
+		api: func(a *APIClient) {},
+		track: func(c *CustomerIO) {
+			c.validateAttributes = true
+		},
+	}
+}
+
+// WithRateLimits makes the client pace its requests to stay within limits.
+// Requests are not rate limited unless this option is given; pass
+// DefaultRateLimits to use Customer.io's default limits. Each client paces
+// its own requests independently.
+func WithRateLimits(limits RateLimits) option {
+	return option{
+		api: func(a *APIClient) {
+			a.limits = newClientLimiter(limits)
+		},
+		track: func(c *CustomerIO) {
+			c.limits = newClientLimiter(limits)
+		},
+	}
+}
+
+// WithRequestObserver sets a function called after every request with its
+// metrics, including the tag set on the request's context with
+// WithRequestTag. The function is called synchronously, so it should not
+// block.
+func WithRequestObserver(observer func(ctx context.Context, m RequestMetrics)) option {
+	return option{
+		api: func(a *APIClient) {
+			a.observer = observer
+		},
+		track: func(c *CustomerIO) {
+			c.observer = observer
+		},
+	}
+}
+
+// WithMetrics sets the Metrics that record the latency and errors of every
+// request. By default nothing is recorded.
+func WithMetrics(m Metrics) option {
+	if m == nil {
+		m = nopMetrics{}
+	}
+	return option{
+		api: func(a *APIClient) {
+			a.metrics = m
+		},
+		track: func(c *CustomerIO) {
+			c.metrics = m
+		},
+	}
+}
+
+// WithTraceHeaderExtractor sets a function called for every request with its
+// context, returning headers to add to the request, such as a W3C
+// traceparent header for distributed tracing. The headers replace any the
+// client would otherwise send with the same names.
+func WithTraceHeaderExtractor(extract func(ctx context.Context) http.Header) option {
+	return option{
+		api: func(a *APIClient) {
+			a.traceHeaders = extract
+		},
+		track: func(c *CustomerIO) {
+			c.traceHeaders = extract
+		},
+	}
+}
+
+// WithResponseDecompression asks Customer.io to gzip responses, and
+// decompresses them before they are decoded. This reduces the bandwidth used
+// by large reads such as searches and exports.
+func WithResponseDecompression() option {
+	return option{
+		api: func(a *APIClient) {
+			a.decompress = true
+		},
+		track: func(c *CustomerIO) {
+			c.decompress = true
+		},
+	}
+}
+
+// WithObjectTypeCache makes the App API client cache the list of object types
+// used to resolve slugs in GetObjectTypeBySlug for ttl, rather than listing
+// them on every lookup. The cache belongs to the client and can be reloaded
+// with RefreshObjectTypes. It has no effect on the track client.
+func WithObjectTypeCache(ttl time.Duration) option {
+	return option{
+		api: func(a *APIClient) {
+			a.objectTypes = &objectTypeCache{ttl: ttl}
+		},
+		track: func(c *CustomerIO) {},
+	}
+}
@@ -1,9 +1,14 @@
 package customerio_test
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/customerio/go-customerio/v3"
 )
@@ -57,3 +62,305 @@ func TestTrackOptions(t *testing.T) {
 		t.Errorf("wrong user-agent. got: %s, want: %s", client.UserAgent, customUserAgent)
 	}
 }
+
+func TestRetryableStatusCodes(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(520)
+			return
+		}
+		w.Write([]byte(`{"segment":{"id":1}}`))
+	}))
+	defer srv.Close()
+
+	client := customerio.NewAPIClient("mykey", customerio.WithRetries(1))
+	client.URL = srv.URL
+	if _, err := client.GetSegment(context.Background(), 1); err == nil {
+		t.Error("expected 520 not to be retried by default")
+	}
+	if attempts != 1 {
+		t.Errorf("wrong attempts. got: %d, want: 1", attempts)
+	}
+
+	attempts = 0
+	client = customerio.NewAPIClient("mykey",
+		customerio.WithRetries(2),
+		customerio.WithRetryableStatusCodes(append(customerio.DefaultRetryableStatusCodes, 520)...))
+	client.URL = srv.URL
+	if _, err := client.GetSegment(context.Background(), 1); err != nil {
+		t.Error(err)
+	}
+	if attempts != 3 {
+		t.Errorf("wrong attempts. got: %d, want: 3", attempts)
+	}
+}
+
+func TestEndpointTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+	}))
+	defer srv.Close()
+
+	client := customerio.NewAPIClient("mykey",
+		customerio.WithEndpointTimeout(customerio.EndpointClassTransactional, 10*time.Millisecond))
+	client.URL = srv.URL
+
+	start := time.Now()
+	_, err := client.SendEmail(context.Background(), &customerio.SendEmailRequest{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("timeout not applied, took %s", elapsed)
+	}
+}
+
+func TestConnectionPool(t *testing.T) {
+	pool := customerio.WithConnectionPool(10, 20, time.Minute)
+
+	track := customerio.NewTrackClient("site_id", "api_key", pool)
+	transport := track.Client.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 10 || transport.MaxConnsPerHost != 20 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("pool not configured on track client: %#v", transport)
+	}
+
+	api := customerio.NewAPIClient("mykey", pool)
+	if api.Client == http.DefaultClient {
+		t.Fatal("expected a dedicated http client")
+	}
+	transport = api.Client.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 10 || transport.MaxConnsPerHost != 20 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("pool not configured on api client: %#v", transport)
+	}
+
+	hc := &http.Client{}
+	api = customerio.NewAPIClient("mykey", pool, customerio.WithHTTPClient(hc))
+	if api.Client != hc || hc.Transport != nil {
+		t.Error("custom http client should be left untouched")
+	}
+}
+
+func TestRequestObserver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"segment":{"id":1}}`))
+	}))
+	defer srv.Close()
+
+	var got []customerio.RequestMetrics
+	client := customerio.NewAPIClient("mykey", customerio.WithRequestObserver(func(ctx context.Context, m customerio.RequestMetrics) {
+		got = append(got, m)
+	}))
+	client.URL = srv.URL
+
+	ctx := customerio.WithRequestTag(context.Background(), "tenant-1")
+	if _, err := client.GetSegment(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("wrong number of observations: %d", len(got))
+	}
+	m := got[0]
+	if m.Tag != "tenant-1" || m.StatusCode != http.StatusOK || m.URL != "/v1/segments/1" || m.ResponseBytes == 0 {
+		t.Errorf("wrong metrics: %#v", m)
+	}
+}
+
+func TestResponseDecompression(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("wrong Accept-Encoding: %q", req.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"segment":{"id":1,"name":"compressed"}}`))
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	client := customerio.NewAPIClient("mykey", customerio.WithResponseDecompression())
+	client.URL = srv.URL
+
+	segment, err := client.GetSegment(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if segment.Name != "compressed" {
+		t.Errorf("wrong segment: %#v", segment)
+	}
+}
+
+type testMetrics struct {
+	statuses []int
+	errors   []string
+}
+
+func (m *testMetrics) ObserveRequest(endpointClass string, status int, d time.Duration) {
+	m.statuses = append(m.statuses, status)
+}
+
+func (m *testMetrics) IncError(endpointClass, kind string) {
+	m.errors = append(m.errors, endpointClass+":"+kind)
+}
+
+func TestMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/v1/customers/bad" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	m := &testMetrics{}
+	client := customerio.NewTrackClient("siteid", "apikey", customerio.WithMetrics(m))
+	client.URL = srv.URL
+
+	client.Identify("good", nil)
+	client.Identify("bad", nil)
+
+	if want := []int{http.StatusOK, http.StatusServiceUnavailable}; !reflect.DeepEqual(m.statuses, want) {
+		t.Errorf("wrong statuses. got: %v, want: %v", m.statuses, want)
+	}
+	if want := []string{"track:server_error"}; !reflect.DeepEqual(m.errors, want) {
+		t.Errorf("wrong errors. got: %v, want: %v", m.errors, want)
+	}
+}
+
+type traceKey struct{}
+
+func TestTraceHeaderExtractor(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		traceparent = req.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey", customerio.WithTraceHeaderExtractor(func(ctx context.Context) http.Header {
+		h := http.Header{}
+		if id, ok := ctx.Value(traceKey{}).(string); ok {
+			h.Set("traceparent", id)
+		}
+		return h
+	}))
+	client.URL = srv.URL
+
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := context.WithValue(context.Background(), traceKey{}, want)
+	if err := client.IdentifyCtx(ctx, "1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if traceparent != want {
+		t.Errorf("wrong traceparent. got: %q, want: %q", traceparent, want)
+	}
+}
+
+func TestUserAgentSuffix(t *testing.T) {
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		userAgent = req.UserAgent()
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey")
+	client.URL = srv.URL
+
+	ctx := customerio.WithUserAgentSuffixCtx(context.Background(), "tenant-a/1.0")
+	if err := client.DeleteCtx(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := customerio.DefaultUserAgent + " tenant-a/1.0"; userAgent != want {
+		t.Errorf("wrong user agent. got: %q, want: %q", userAgent, want)
+	}
+
+	if err := client.DeleteCtx(context.Background(), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if userAgent != customerio.DefaultUserAgent {
+		t.Errorf("wrong user agent. got: %q, want: %q", userAgent, customerio.DefaultUserAgent)
+	}
+}
+
+func TestMaxCallDuration(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+	}))
+	defer srv.Close()
+
+	client := customerio.NewTrackClient("siteid", "apikey", customerio.WithMaxCallDuration(10*time.Millisecond))
+	client.URL = srv.URL
+
+	start := time.Now()
+	if err := client.Identify("1", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("max call duration not applied, took %s", elapsed)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"segments":[]}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey", customerio.WithRateLimits(customerio.RateLimits{App: 20}))
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := api.ListSegments(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// At 20 requests per second, requests are 50ms apart, and the first is
+	// sent immediately.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("requests were not paced. 4 requests took %v, want at least 150ms", elapsed)
+	}
+
+	limits, err := api.RateLimits(ctx)
+	if err != nil || limits.App != 20 {
+		t.Errorf("wrong limits. got: %+v, %v", limits, err)
+	}
+}
+
+func TestRateLimitsCanceled(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.Write([]byte(`{"segments":[]}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey", customerio.WithRateLimits(customerio.RateLimits{App: 0.1}))
+	api.URL = srv.URL
+
+	if _, err := api.ListSegments(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next request must wait ten seconds, so it should give up as soon
+	// as its context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := api.ListSegments(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("canceled request waited %v", elapsed)
+	}
+	if requests != 1 {
+		t.Errorf("wrong number of requests. got: %d, want: 1", requests)
+	}
+}
@@ -0,0 +1,58 @@
+package customerio
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// PageResult is a single page of a paginated listing. NextCursor can be
+// passed to the same method to read the following page, and is empty after
+// the last page. Cursors can be persisted to resume a listing later.
+type PageResult[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// maxPageSize is the largest page size accepted by the paginated endpoints.
+const maxPageSize = 1000
+
+// collectPages reads pages with fetch, starting from the first, until the
+// listing is exhausted or limit items have been read. A limit of zero or less
+// reads every page.
+func collectPages[T any](limit int, fetch func(cursor string, pageSize int) (PageResult[T], error)) ([]T, error) {
+	var (
+		items  []T
+		cursor string
+	)
+	for {
+		pageSize := maxPageSize
+		if limit > 0 {
+			pageSize = min(pageSize, limit-len(items))
+		}
+		page, err := fetch(cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+
+		if limit > 0 && len(items) >= limit {
+			return items[:limit], nil
+		}
+		if page.NextCursor == "" || len(page.Items) == 0 {
+			return items, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// pageValues returns the query parameters requesting a page.
+func pageValues(cursor string, limit int) url.Values {
+	v := url.Values{}
+	if limit > 0 {
+		v.Add("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		v.Add("start", cursor)
+	}
+	return v
+}
@@ -0,0 +1,105 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestGetSegmentMembershipPages(t *testing.T) {
+	var starts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/segments/7/membership" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		start := req.URL.Query().Get("start")
+		starts = append(starts, start)
+		switch start {
+		case "":
+			w.Write([]byte(`{"identifiers":[{"cio_id":"a","id":1}],"next":"p2"}`))
+		case "p2":
+			w.Write([]byte(`{"identifiers":[{"cio_id":"b","id":"2","email":"b@example.com"}],"next":"p3"}`))
+		default:
+			// An empty page ends the listing even if it has a cursor.
+			w.Write([]byte(`{"identifiers":[],"next":"p4"}`))
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	page, err := api.GetSegmentMembershipPage(ctx, 7, "p2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := customerio.PageResult[customerio.SegmentMember]{
+		Items:      []customerio.SegmentMember{{CioID: "b", ID: "2", Email: "b@example.com"}},
+		NextCursor: "p3",
+	}
+	if !reflect.DeepEqual(page, want) {
+		t.Errorf("wrong page. got: %#v, want: %#v", page, want)
+	}
+
+	starts = nil
+	members, err := api.GetSegmentMembership(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMembers := []customerio.SegmentMember{{CioID: "a", ID: "1"}, {CioID: "b", ID: "2", Email: "b@example.com"}}
+	if !reflect.DeepEqual(members, wantMembers) {
+		t.Errorf("wrong members. got: %v, want: %v", members, wantMembers)
+	}
+	if wantStarts := []string{"", "p2", "p3"}; !reflect.DeepEqual(starts, wantStarts) {
+		t.Errorf("wrong cursors. got: %q, want: %q", starts, wantStarts)
+	}
+}
+
+func TestSearchCustomersPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != "/v1/customers" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		if got := req.URL.Query().Get("start"); got != "p2" {
+			t.Errorf("wrong cursor. got: %s, want: p2", got)
+		}
+		if got := req.URL.Query().Get("limit"); got != "5" {
+			t.Errorf("wrong limit. got: %s, want: 5", got)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		want := map[string]any{"filter": map[string]any{"and": []any{
+			map[string]any{"attribute": map[string]any{"field": "plan", "operator": "eq", "value": "pro"}},
+		}}}
+		if !reflect.DeepEqual(body, want) {
+			t.Errorf("wrong body. got: %v, want: %v", body, want)
+		}
+		w.Write([]byte(`{"identifiers":[{"cio_id":"a","id":42,"email":"a@example.com"}],"next":"p3"}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	page, err := api.SearchCustomersPage(context.Background(), customerio.Filter{
+		And: []customerio.AttributeCondition{customerio.NewEqAttribute("plan", "pro")},
+	}, "p2", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := customerio.PageResult[customerio.Customer]{
+		Items:      []customerio.Customer{{CioID: "a", ID: "42", Email: "a@example.com"}},
+		NextCursor: "p3",
+	}
+	if !reflect.DeepEqual(page, want) {
+		t.Errorf("wrong page. got: %#v, want: %#v", page, want)
+	}
+}
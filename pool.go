@@ -0,0 +1,30 @@
+package customerio
+
+import (
+	"net/http"
+	"time"
+)
+
+// connectionPool holds the transport settings set by WithConnectionPool.
+type connectionPool struct {
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleTimeout         time.Duration
+}
+
+// configure applies the pool settings to t. Zero values leave the existing
+// settings in place.
+func (p *connectionPool) configure(t *http.Transport) {
+	if p.maxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = p.maxIdleConnsPerHost
+		if t.MaxIdleConns > 0 && t.MaxIdleConns < p.maxIdleConnsPerHost {
+			t.MaxIdleConns = p.maxIdleConnsPerHost
+		}
+	}
+	if p.maxConnsPerHost > 0 {
+		t.MaxConnsPerHost = p.maxConnsPerHost
+	}
+	if p.idleTimeout > 0 {
+		t.IdleConnTimeout = p.idleTimeout
+	}
+}
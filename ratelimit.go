@@ -0,0 +1,108 @@
+package customerio
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimits are request rates, in requests per second, for each of the
+// Customer.io APIs. A zero rate is not limited.
+type RateLimits struct {
+	Track         float64
+	App           float64
+	Transactional float64
+}
+
+// DefaultRateLimits are the documented default limits for Customer.io
+// workspaces.
+var DefaultRateLimits = RateLimits{
+	Track:         100,
+	App:           10,
+	Transactional: 100,
+}
+
+// RateLimits returns the rate limits the client enforces, as set with
+// WithRateLimits, or DefaultRateLimits if none were set. Customer.io does not
+// expose a workspace's limits through the API, so these can't be read from
+// the account; if your plan's limits differ from the defaults, configure them
+// with WithRateLimits.
+func (c *APIClient) RateLimits(ctx context.Context) (RateLimits, error) {
+	if c.limits == nil {
+		return DefaultRateLimits, nil
+	}
+	return c.limits.limits, nil
+}
+
+// clientLimiter paces requests according to RateLimits.
+type clientLimiter struct {
+	limits        RateLimits
+	track         *rateLimiter
+	app           *rateLimiter
+	transactional *rateLimiter
+}
+
+func newClientLimiter(l RateLimits) *clientLimiter {
+	return &clientLimiter{
+		limits:        l,
+		track:         newRateLimiter(l.Track),
+		app:           newRateLimiter(l.App),
+		transactional: newRateLimiter(l.Transactional),
+	}
+}
+
+// wait blocks until a request to an endpoint of class may be sent.
+func (l *clientLimiter) wait(ctx context.Context, class EndpointClass) error {
+	if l == nil {
+		return nil
+	}
+	switch class {
+	case EndpointClassTrack, EndpointClassBatch:
+		return l.track.wait(ctx)
+	case EndpointClassTransactional:
+		return l.transactional.wait(ctx)
+	}
+	return l.app.wait(ctx)
+}
+
+// rateLimiter spaces requests evenly at a fixed rate.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+package customerio
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRetryableStatusCodes are the response status codes retried when
+// retries are enabled with WithRetries.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+const (
+	retryMinBackoff = 100 * time.Millisecond
+	retryMaxBackoff = 5 * time.Second
+)
+
+// retryPolicy controls whether a failed request is sent again. The zero value
+// never retries.
+type retryPolicy struct {
+	maxRetries  int
+	statusCodes map[int]bool
+	budget      *retryBudget
+}
+
+func defaultRetryPolicy() retryPolicy {
+	p := retryPolicy{}
+	p.setStatusCodes(DefaultRetryableStatusCodes)
+	return p
+}
+
+func (p *retryPolicy) setStatusCodes(codes []int) {
+	p.statusCodes = make(map[int]bool, len(codes))
+	for _, code := range codes {
+		p.statusCodes[code] = true
+	}
+}
+
+// retryable reports whether a request with the given method that received
+// statusCode may be sent again. POST requests are never retried: the client
+// has no way to attach an idempotency key, so a retried POST could be applied
+// twice by the server.
+func (p retryPolicy) retryable(method string, statusCode int) bool {
+	if method == http.MethodPost {
+		return false
+	}
+	return p.statusCodes[statusCode]
+}
+
+// do sends the request built by newReq, rebuilding and resending it while the
+// response is retryable and retries remain. newReq is called once per attempt
+// so that request bodies can be replayed. If the retry budget prevents a
+// retry, exhausted is called and the failed response is returned.
+func (p retryPolicy) do(ctx context.Context, client *http.Client, newReq func() (*http.Request, error), exhausted func()) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			p.budget.deposit(time.Now())
+		}
+		if attempt >= p.maxRetries || !p.retryable(req.Method, resp.StatusCode) {
+			return resp, nil
+		}
+		if !p.budget.withdraw(time.Now()) {
+			exhausted()
+			return resp, nil
+		}
+
+		wait := retryBackoff(attempt, resp)
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryBackoff returns how long to wait before the next attempt, preferring
+// the server's Retry-After header when it is given in seconds.
+func retryBackoff(attempt int, resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+		return min(time.Duration(s)*time.Second, retryMaxBackoff)
+	}
+	if attempt >= 16 {
+		return retryMaxBackoff
+	}
+	return min(retryMinBackoff<<attempt, retryMaxBackoff)
+}
+
+// retryBudgetWindow is the number of seconds of history a retryBudget
+// considers.
+const retryBudgetWindow = 10
+
+// retryBudget caps the retries made by a client at a fraction of its recent
+// successful requests, plus a minimum rate, so that retries can't multiply
+// the load on an API that is already failing. A nil budget allows every
+// retry.
+type retryBudget struct {
+	ratio     float64
+	minPerSec int
+
+	mu      sync.Mutex
+	buckets [retryBudgetWindow]retryBudgetBucket
+}
+
+// retryBudgetBucket counts the successes and retries in one second.
+type retryBudgetBucket struct {
+	sec       int64
+	successes int
+	retries   int
+}
+
+func (b *retryBudget) bucket(now time.Time) *retryBudgetBucket {
+	sec := now.Unix()
+	bucket := &b.buckets[sec%retryBudgetWindow]
+	if bucket.sec != sec {
+		*bucket = retryBudgetBucket{sec: sec}
+	}
+	return bucket
+}
+
+// deposit records a successful request, earning a fraction of a retry.
+func (b *retryBudget) deposit(now time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bucket(now).successes++
+}
+
+// withdraw reports whether a retry is within the budget, recording it if so.
+func (b *retryBudget) withdraw(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var successes, retries int
+	for _, bucket := range b.buckets {
+		if now.Unix()-bucket.sec < retryBudgetWindow {
+			successes += bucket.successes
+			retries += bucket.retries
+		}
+	}
+	allowed := float64(b.minPerSec*retryBudgetWindow) + b.ratio*float64(successes)
+	if float64(retries+1) > allowed {
+		return false
+	}
+	b.bucket(now).retries++
+	return true
+}
@@ -0,0 +1,47 @@
+package customerio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	b := &retryBudget{ratio: 0.5, minPerSec: 0}
+	now := time.Unix(1600000000, 0)
+
+	if b.withdraw(now) {
+		t.Error("retry allowed with no successes")
+	}
+	for i := 0; i < 4; i++ {
+		b.deposit(now)
+	}
+	for i := 0; i < 2; i++ {
+		if !b.withdraw(now) {
+			t.Errorf("retry %d refused within budget", i)
+		}
+	}
+	if b.withdraw(now) {
+		t.Error("retry allowed beyond budget")
+	}
+
+	// Once the window has passed, the earlier successes no longer count.
+	later := now.Add(retryBudgetWindow * time.Second)
+	if b.withdraw(later) {
+		t.Error("retry allowed after successes left the window")
+	}
+
+	b = &retryBudget{ratio: 0, minPerSec: 1}
+	for i := 0; i < retryBudgetWindow; i++ {
+		if !b.withdraw(now) {
+			t.Fatalf("retry %d refused within the minimum rate", i)
+		}
+	}
+	if b.withdraw(now) {
+		t.Error("retry allowed beyond the minimum rate")
+	}
+
+	var nilBudget *retryBudget
+	if !nilBudget.withdraw(now) {
+		t.Error("nil budget should allow retries")
+	}
+}
@@ -3,19 +3,72 @@ package customerio
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
+// SegmentState is the build state of a segment.
+type SegmentState string
+
+const (
+	SegmentStateEvents   SegmentState = "events"
+	SegmentStateBuild    SegmentState = "build"
+	SegmentStateFinished SegmentState = "finished"
+)
+
+// UnmarshalJSON decodes a segment state, preserving values without a
+// corresponding constant.
+func (s *SegmentState) UnmarshalJSON(b []byte) error {
+	var v *string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v != nil {
+		*s = SegmentState(*v)
+	}
+	return nil
+}
+
+// SegmentType is the kind of segment.
+type SegmentType string
+
+const (
+	SegmentTypeDynamic SegmentType = "dynamic"
+	SegmentTypeManual  SegmentType = "manual"
+)
+
+// UnmarshalJSON decodes a segment type, preserving values without a
+// corresponding constant.
+func (t *SegmentType) UnmarshalJSON(b []byte) error {
+	var v *string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v != nil {
+		*t = SegmentType(*v)
+	}
+	return nil
+}
+
 type Segment struct {
-	ID          int    `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	State       string `json:"state,omitempty"`
-	Type        string `json:"type,omitempty"`
+	ID          int          `json:"id,omitempty"`
+	Name        string       `json:"name,omitempty"`
+	Description string       `json:"description,omitempty"`
+	State       SegmentState `json:"state,omitempty"`
+	Type        SegmentType  `json:"type,omitempty"`
 }
 
+// ListSegments returns every segment in the workspace.
 func (c *APIClient) ListSegments(ctx context.Context) ([]Segment, error) {
+	return c.ListSegmentsLimit(ctx, 0)
+}
+
+// ListSegmentsLimit is like ListSegments but returns at most limit segments.
+// The segments endpoint is not paginated, so the limit is applied after the
+// response is read. A limit of zero or less returns every segment.
+func (c *APIClient) ListSegmentsLimit(ctx context.Context, limit int) ([]Segment, error) {
 	body, statusCode, err := c.doRequest(ctx, "GET", "/v1/segments", nil)
 	if err != nil {
 		return nil, err
@@ -30,10 +83,16 @@ func (c *APIClient) ListSegments(ctx context.Context) ([]Segment, error) {
 	if err := json.Unmarshal(body, &envelope); err != nil {
 		return nil, err
 	}
+	if limit > 0 && len(envelope.Segments) > limit {
+		return envelope.Segments[:limit], nil
+	}
 	return envelope.Segments, nil
 }
 
 func (c *APIClient) GetSegment(ctx context.Context, id int) (Segment, error) {
+	if id <= 0 {
+		return Segment{}, ParamError{Param: "segmentID"}
+	}
 	body, statusCode, err := c.doRequest(ctx, "GET", fmt.Sprintf("/v1/segments/%d", id), nil)
 	if err != nil {
 		return Segment{}, err
@@ -50,3 +109,354 @@ func (c *APIClient) GetSegment(ctx context.Context, id int) (Segment, error) {
 	}
 	return envelope.Segment, nil
 }
+
+// Dependency is a campaign or newsletter that uses a segment.
+type Dependency struct {
+	// Type is "campaign", "sent_newsletter" or "draft_newsletter".
+	Type string
+	ID   int
+}
+
+// ErrSegmentInUse is returned by DeleteSegment when campaigns or newsletters
+// still use the segment.
+var ErrSegmentInUse = errors.New("segment is in use")
+
+// GetSegmentDependencies returns the campaigns and newsletters that use the
+// segment.
+func (c *APIClient) GetSegmentDependencies(ctx context.Context, id int) ([]Dependency, error) {
+	if id <= 0 {
+		return nil, ParamError{Param: "segmentID"}
+	}
+	url := fmt.Sprintf("/v1/segments/%d/used_by", id)
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var envelope struct {
+		UsedBy struct {
+			Campaigns        []int `json:"campaigns"`
+			SentNewsletters  []int `json:"sent_newsletters"`
+			DraftNewsletters []int `json:"draft_newsletters"`
+		} `json:"used_by"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+
+	var deps []Dependency
+	for _, id := range envelope.UsedBy.Campaigns {
+		deps = append(deps, Dependency{Type: "campaign", ID: id})
+	}
+	for _, id := range envelope.UsedBy.SentNewsletters {
+		deps = append(deps, Dependency{Type: "sent_newsletter", ID: id})
+	}
+	for _, id := range envelope.UsedBy.DraftNewsletters {
+		deps = append(deps, Dependency{Type: "draft_newsletter", ID: id})
+	}
+	return deps, nil
+}
+
+// DeleteSegment deletes a segment. Unless force is set, the segment's
+// dependencies are checked first and ErrSegmentInUse is returned if any
+// campaigns or newsletters use it.
+func (c *APIClient) DeleteSegment(ctx context.Context, id int, force bool) error {
+	if id <= 0 {
+		return ParamError{Param: "segmentID"}
+	}
+	if !force {
+		deps, err := c.GetSegmentDependencies(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(deps) > 0 {
+			return ErrSegmentInUse
+		}
+	}
+
+	url := fmt.Sprintf("/v1/segments/%d", id)
+	body, statusCode, err := c.doRequest(ctx, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK && statusCode != http.StatusNoContent {
+		return &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+	return nil
+}
+
+// SegmentMember is a single customer in a segment's membership, identified by
+// whichever identifiers the workspace has for them.
+type SegmentMember struct {
+	CioID string `json:"cio_id,omitempty"`
+	Email string `json:"email,omitempty"`
+	ID    string `json:"id,omitempty"`
+}
+
+func (m *SegmentMember) UnmarshalJSON(b []byte) error {
+	var r struct {
+		CioID jsonID `json:"cio_id"`
+		Email string `json:"email"`
+		ID    jsonID `json:"id"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	*m = SegmentMember{CioID: string(r.CioID), Email: r.Email, ID: string(r.ID)}
+	return nil
+}
+
+func (m SegmentMember) identifier(idType IdentifierType) string {
+	switch idType {
+	case IdentifierTypeID:
+		return m.ID
+	case IdentifierTypeEmail:
+		return m.Email
+	case IdentifierTypeCioID:
+		return m.CioID
+	}
+	return ""
+}
+
+// GetSegmentMembership returns every customer in the segment, following the
+// membership endpoint's pagination until it is exhausted.
+func (c *APIClient) GetSegmentMembership(ctx context.Context, segmentID int) ([]SegmentMember, error) {
+	return c.GetSegmentMembershipLimit(ctx, segmentID, 0)
+}
+
+// GetSegmentMembershipLimit is like GetSegmentMembership but returns at most
+// limit members. A limit of zero or less reads every page.
+func (c *APIClient) GetSegmentMembershipLimit(ctx context.Context, segmentID int, limit int) ([]SegmentMember, error) {
+	if segmentID <= 0 {
+		return nil, ParamError{Param: "segmentID"}
+	}
+	return collectPages(limit, func(cursor string, pageSize int) (PageResult[SegmentMember], error) {
+		return c.GetSegmentMembershipPage(ctx, segmentID, cursor, pageSize)
+	})
+}
+
+// GetSegmentMembershipPage returns a single page of the segment's members,
+// starting at cursor, or at the first page if cursor is empty. A limit of
+// zero or less uses the API's default page size.
+func (c *APIClient) GetSegmentMembershipPage(ctx context.Context, segmentID int, cursor string, limit int) (PageResult[SegmentMember], error) {
+	if segmentID <= 0 {
+		return PageResult[SegmentMember]{}, ParamError{Param: "segmentID"}
+	}
+	url := fmt.Sprintf("/v1/segments/%d/membership?%s", segmentID, pageValues(cursor, limit).Encode())
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return PageResult[SegmentMember]{}, err
+	}
+	if statusCode != http.StatusOK {
+		return PageResult[SegmentMember]{}, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var page struct {
+		Identifiers []SegmentMember `json:"identifiers"`
+		Next        string          `json:"next"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return PageResult[SegmentMember]{}, err
+	}
+	return PageResult[SegmentMember]{Items: page.Identifiers, NextCursor: page.Next}, nil
+}
+
+// segmentMembershipBatchSize is the maximum number of ids accepted by a single
+// add_customers or remove_customers call.
+const segmentMembershipBatchSize = 1000
+
+// SyncSegmentMembership makes the membership of a manual segment match
+// desired exactly. Current membership is read with the App API, and the
+// additions and removals are written in batches with the track client set
+// with WithTrackClient, since manual segment membership can only be changed
+// through the Track API. Emails are compared case-insensitively. Members
+// without a value for idType can't be in desired, so they are removed by
+// their cio_id. The counts of identities added and removed are returned; on
+// error they reflect the batches that succeeded.
+func (c *APIClient) SyncSegmentMembership(ctx context.Context, segmentID int, desired []string, idType IdentifierType) (added, removed int, err error) {
+	if segmentID <= 0 {
+		return 0, 0, ParamError{Param: "segmentID"}
+	}
+	track, err := c.trackClient()
+	if err != nil {
+		return 0, 0, err
+	}
+	normalize := func(id string) string {
+		if idType == IdentifierTypeEmail {
+			return strings.ToLower(id)
+		}
+		return id
+	}
+
+	members, err := c.GetSegmentMembership(ctx, segmentID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	current := map[string]bool{}
+	for _, m := range members {
+		if id := m.identifier(idType); id != "" {
+			current[normalize(id)] = true
+		}
+	}
+
+	want := map[string]bool{}
+	var toAdd []string
+	for _, id := range desired {
+		n := normalize(id)
+		if n == "" || want[n] {
+			continue
+		}
+		want[n] = true
+		if !current[n] {
+			toAdd = append(toAdd, id)
+		}
+	}
+
+	toRemove := map[IdentifierType][]string{}
+	for _, m := range members {
+		if id := m.identifier(idType); id != "" {
+			if !want[normalize(id)] {
+				toRemove[idType] = append(toRemove[idType], id)
+			}
+		} else if m.CioID != "" {
+			toRemove[IdentifierTypeCioID] = append(toRemove[IdentifierTypeCioID], m.CioID)
+		}
+	}
+
+	for len(toAdd) > 0 {
+		n := min(len(toAdd), segmentMembershipBatchSize)
+		if err := track.updateSegmentMembership(ctx, "add_customers", segmentID, toAdd[:n], idType); err != nil {
+			return added, removed, err
+		}
+		added += n
+		toAdd = toAdd[n:]
+	}
+	removeTypes := []IdentifierType{idType}
+	if idType != IdentifierTypeCioID {
+		removeTypes = append(removeTypes, IdentifierTypeCioID)
+	}
+	for _, removeType := range removeTypes {
+		ids := toRemove[removeType]
+		for len(ids) > 0 {
+			n := min(len(ids), segmentMembershipBatchSize)
+			if err := track.updateSegmentMembership(ctx, "remove_customers", segmentID, ids[:n], removeType); err != nil {
+				return added, removed, err
+			}
+			removed += n
+			ids = ids[n:]
+		}
+	}
+
+	return added, removed, nil
+}
+
+// PopulateSegmentFromFilter adds every customer matching filter to a manual
+// segment. Matching customers are collected first and then added in batches
+// with the track client set by WithTrackClient, since manual segment
+// membership can only be changed through the Track API. Customers without a
+// value for idType are skipped. The number of identities added is returned;
+// on error it reflects the batches that succeeded.
+func (c *APIClient) PopulateSegmentFromFilter(ctx context.Context, segmentID int, filter Filter, idType IdentifierType) (int, error) {
+	if segmentID <= 0 {
+		return 0, ParamError{Param: "segmentID"}
+	}
+	if filter.empty() {
+		return 0, ParamError{Param: "filter"}
+	}
+	track, err := c.trackClient()
+	if err != nil {
+		return 0, err
+	}
+
+	customers, err := collectPages(0, func(cursor string, pageSize int) (PageResult[Customer], error) {
+		return c.SearchCustomersPage(ctx, filter, cursor, pageSize)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	seen := map[string]bool{}
+	var ids []string
+	for _, id := range customerIdentifiers(customers, idType) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	added := 0
+	for len(ids) > 0 {
+		if err := ctx.Err(); err != nil {
+			return added, err
+		}
+		n := min(len(ids), segmentMembershipBatchSize)
+		if err := track.updateSegmentMembership(ctx, "add_customers", segmentID, ids[:n], idType); err != nil {
+			return added, err
+		}
+		added += n
+		ids = ids[n:]
+	}
+	return added, nil
+}
+
+// RemoveCustomersFromSegment removes customers from an existing manual
+// segment. Like AddCustomersToSegment, customers without a value for the
+// specified identifier are skipped, and the first return value is the number
+// of identities that we attempted to remove from the segment.
+func (c *CustomerIO) RemoveCustomersFromSegment(ctx context.Context, segmentID int, customers []Customer, identifier IdentifierType) (int, error) {
+	identifiers := customerIdentifiers(customers, identifier)
+	err := c.updateSegmentMembership(ctx, "remove_customers", segmentID, identifiers, identifier)
+	return len(identifiers), err
+}
+
+func customerIdentifiers(customers []Customer, identifier IdentifierType) []string {
+	identifiers := make([]string, 0, len(customers))
+	for _, customer := range customers {
+		if id := customerIdentifier(customer, identifier); id != "" {
+			identifiers = append(identifiers, id)
+		}
+	}
+	return identifiers
+}
+
+func customerIdentifier(customer Customer, identifier IdentifierType) string {
+	switch identifier {
+	case IdentifierTypeID:
+		return customer.ID
+	case IdentifierTypeEmail:
+		return customer.Email
+	case IdentifierTypeCioID:
+		return customer.CioID
+	}
+	return ""
+}
+
+// PartitionByIdentifier splits customers into those with a value for idType
+// and those without, preserving their order.
+func PartitionByIdentifier(customers []Customer, idType IdentifierType) (have []Customer, missing []Customer) {
+	for _, customer := range customers {
+		if customerIdentifier(customer, idType) != "" {
+			have = append(have, customer)
+		} else {
+			missing = append(missing, customer)
+		}
+	}
+	return have, missing
+}
+
+func (c *CustomerIO) updateSegmentMembership(ctx context.Context, action string, segmentID int, ids []string, identifier IdentifierType) error {
+	if segmentID <= 0 {
+		return ParamError{Param: "segmentID"}
+	}
+	_, err := c.request(ctx, http.MethodPost,
+		fmt.Sprintf("%s/api/v1/segments/%d/%s?id_type=%s", c.URL, segmentID, action, identifier),
+		map[string]interface{}{
+			"ids": ids,
+		},
+	)
+	return err
+}
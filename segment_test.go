@@ -0,0 +1,306 @@
+package customerio_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestSyncSegmentMembership(t *testing.T) {
+	changes := map[string][]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/segments/7/membership":
+			if req.URL.Query().Get("start") == "" {
+				w.Write([]byte(`{"identifiers":[{"email":"Keep@example.com","cio_id":"a"},{"email":"drop@example.com","cio_id":"b"}],"next":"page2"}`))
+				return
+			}
+			w.Write([]byte(`{"identifiers":[{"cio_id":"c"}],"next":""}`))
+		case "/api/v1/segments/7/add_customers", "/api/v1/segments/7/remove_customers":
+			b, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			var body struct {
+				IDs []string `json:"ids"`
+			}
+			if err := json.Unmarshal(b, &body); err != nil {
+				t.Error(err)
+			}
+			key := req.URL.Path + "?id_type=" + req.URL.Query().Get("id_type")
+			changes[key] = append(changes[key], body.IDs...)
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+	api := customerio.NewAPIClient("myKey", customerio.WithTrackClient(track))
+	api.URL = srv.URL
+
+	added, removed, err := api.SyncSegmentMembership(context.Background(), 7,
+		[]string{"keep@example.com", "new@example.com", "new@example.com"}, customerio.IdentifierTypeEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 || removed != 2 {
+		t.Errorf("wrong counts. got: added=%d removed=%d, want: added=1 removed=2", added, removed)
+	}
+
+	want := map[string][]string{
+		"/api/v1/segments/7/add_customers?id_type=email":     {"new@example.com"},
+		"/api/v1/segments/7/remove_customers?id_type=email":  {"drop@example.com"},
+		"/api/v1/segments/7/remove_customers?id_type=cio_id": {"c"},
+	}
+	for _, ids := range changes {
+		sort.Strings(ids)
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("wrong changes. got: %v, want: %v", changes, want)
+	}
+
+	_, _, err = customerio.NewAPIClient("myKey").SyncSegmentMembership(context.Background(), 7, nil, customerio.IdentifierTypeEmail)
+	checkParamError(t, err, "track")
+}
+
+func TestSegmentIDValidation(t *testing.T) {
+	ctx := context.Background()
+	track := customerio.NewTrackClient("siteid", "apikey")
+	api := customerio.NewAPIClient("myKey", customerio.WithTrackClient(track))
+
+	for _, id := range []int{0, -1} {
+		_, err := api.GetSegment(ctx, id)
+		checkParamError(t, err, "segmentID")
+
+		_, err = api.GetSegmentMembership(ctx, id)
+		checkParamError(t, err, "segmentID")
+
+		_, _, err = api.SyncSegmentMembership(ctx, id, nil, customerio.IdentifierTypeID)
+		checkParamError(t, err, "segmentID")
+
+		_, err = track.AddCustomersToSegment(ctx, id, nil, customerio.IdentifierTypeID)
+		checkParamError(t, err, "segmentID")
+
+		_, err = track.RemoveCustomersFromSegment(ctx, id, nil, customerio.IdentifierTypeID)
+		checkParamError(t, err, "segmentID")
+	}
+}
+
+func TestSegmentEnums(t *testing.T) {
+	var segment customerio.Segment
+	if err := json.Unmarshal([]byte(`{"id":1,"state":"finished","type":"manual"}`), &segment); err != nil {
+		t.Fatal(err)
+	}
+	if segment.State != customerio.SegmentStateFinished || segment.Type != customerio.SegmentTypeManual {
+		t.Errorf("wrong segment: %#v", segment)
+	}
+
+	if err := json.Unmarshal([]byte(`{"id":1,"state":"archived","type":null}`), &segment); err != nil {
+		t.Fatal(err)
+	}
+	if segment.State != "archived" {
+		t.Errorf("unknown state not preserved. got: %s", segment.State)
+	}
+}
+
+func TestDeleteSegment(t *testing.T) {
+	var deleted bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "GET" && req.URL.Path == "/v1/segments/7/used_by":
+			w.Write([]byte(`{"used_by":{"campaigns":[3],"sent_newsletters":[],"draft_newsletters":[4]}}`))
+		case req.Method == "DELETE" && req.URL.Path == "/v1/segments/7":
+			deleted = true
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	deps, err := api.GetSegmentDependencies(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []customerio.Dependency{{Type: "campaign", ID: 3}, {Type: "draft_newsletter", ID: 4}}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("wrong dependencies. got: %v, want: %v", deps, want)
+	}
+
+	if err := api.DeleteSegment(context.Background(), 7, false); err != customerio.ErrSegmentInUse {
+		t.Errorf("expected ErrSegmentInUse, got: %v", err)
+	}
+	if deleted {
+		t.Error("segment in use was deleted")
+	}
+	if err := api.DeleteSegment(context.Background(), 7, true); err != nil {
+		t.Error(err)
+	}
+	if !deleted {
+		t.Error("forced delete did not delete the segment")
+	}
+}
+
+func TestLoadSegmentMembershipSet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(`{"identifiers":[{"email":"A@example.com","id":"1"},{"email":"b@example.com","id":"2"},{"id":"3"}],"next":""}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	set, err := api.LoadSegmentMembershipSet(ctx, 7, customerio.IdentifierTypeEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Len() != 2 {
+		t.Errorf("wrong length. got: %d, want: 2", set.Len())
+	}
+	if !set.Contains("a@example.com") || !set.Contains("B@EXAMPLE.COM") || set.Contains("c@example.com") {
+		t.Error("wrong membership for exact set")
+	}
+
+	filter, err := api.LoadSegmentMembershipFilter(ctx, 7, customerio.IdentifierTypeID, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if !filter.Contains(id) {
+			t.Errorf("filter is missing member %s", id)
+		}
+	}
+}
+
+func TestPartitionByIdentifier(t *testing.T) {
+	customers := []customerio.Customer{
+		{ID: "1", Email: "a@example.com"},
+		{ID: "2"},
+		{ID: "3", Email: "c@example.com"},
+	}
+	have, missing := customerio.PartitionByIdentifier(customers, customerio.IdentifierTypeEmail)
+	if !reflect.DeepEqual(have, []customerio.Customer{customers[0], customers[2]}) {
+		t.Errorf("wrong customers with email. got: %v", have)
+	}
+	if !reflect.DeepEqual(missing, []customerio.Customer{customers[1]}) {
+		t.Errorf("wrong customers without email. got: %v", missing)
+	}
+}
+
+func TestListSegmentsLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/segments" {
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+		}
+		w.Write([]byte(`{"segments":[{"id":1,"name":"a"},{"id":2,"name":"b"},{"id":3,"name":"c"}]}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	segments, err := api.ListSegmentsLimit(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 || segments[0].ID != 1 || segments[1].ID != 2 {
+		t.Errorf("wrong segments. got: %v", segments)
+	}
+
+	segments, err = api.ListSegmentsLimit(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 3 {
+		t.Errorf("wrong number of segments without a limit. got: %d, want: 3", len(segments))
+	}
+}
+
+func TestPopulateSegmentFromFilter(t *testing.T) {
+	var added [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == "POST" && req.URL.Path == "/v1/customers":
+			var body map[string]interface{}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			want := map[string]interface{}{"filter": map[string]interface{}{"and": []interface{}{
+				map[string]interface{}{"attribute": map[string]interface{}{"field": "plan", "operator": "eq", "value": "pro"}},
+				map[string]interface{}{"attribute": map[string]interface{}{"field": "email", "operator": "exists"}},
+			}}}
+			if !reflect.DeepEqual(body, want) {
+				t.Errorf("wrong search body. got: %v, want: %v", body, want)
+			}
+			if req.URL.Query().Get("start") == "" {
+				w.Write([]byte(`{"identifiers":[{"cio_id":"a","id":"1"},{"cio_id":"b"}],"next":"page2"}`))
+				return
+			}
+			w.Write([]byte(`{"identifiers":[{"cio_id":"c","id":2},{"cio_id":"d","id":"1"}],"next":""}`))
+		case req.Method == "POST" && req.URL.Path == "/api/v1/segments/7/add_customers":
+			if idType := req.URL.Query().Get("id_type"); idType != "id" {
+				t.Errorf("wrong id_type. got: %s, want: id", idType)
+			}
+			var body struct {
+				IDs []string `json:"ids"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+			added = append(added, body.IDs)
+		default:
+			t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	track := customerio.NewTrackClient("siteid", "apikey")
+	track.URL = srv.URL
+	api := customerio.NewAPIClient("myKey", customerio.WithTrackClient(track))
+	api.URL = srv.URL
+
+	filter := customerio.Filter{And: []customerio.AttributeCondition{
+		customerio.NewEqAttribute("plan", "pro"),
+		customerio.NewExistsAttribute("email"),
+	}}
+	n, err := api.PopulateSegmentFromFilter(context.Background(), 7, filter, customerio.IdentifierTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("wrong count. got: %d, want: 2", n)
+	}
+	if want := [][]string{{"1", "2"}}; !reflect.DeepEqual(added, want) {
+		t.Errorf("wrong additions. got: %v, want: %v", added, want)
+	}
+
+	added = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := api.PopulateSegmentFromFilter(ctx, 7, filter, customerio.IdentifierTypeID); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+	if len(added) != 0 {
+		t.Errorf("customers were added after cancellation: %v", added)
+	}
+
+	_, err = api.PopulateSegmentFromFilter(context.Background(), 7, customerio.Filter{}, customerio.IdentifierTypeID)
+	checkParamError(t, err, "filter")
+	_, err = customerio.NewAPIClient("myKey").PopulateSegmentFromFilter(context.Background(), 7, filter, customerio.IdentifierTypeID)
+	checkParamError(t, err, "track")
+}
@@ -0,0 +1,165 @@
+package customerio
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// transportConfig holds the request behavior shared by the track and App API
+// clients.
+type transportConfig struct {
+	retry    retryPolicy
+	timeouts map[EndpointClass]time.Duration
+	// maxCallDuration bounds requests whose context has no deadline.
+	maxCallDuration time.Duration
+	pool            *connectionPool
+	limits          *clientLimiter
+	observer        func(context.Context, RequestMetrics)
+	metrics         Metrics
+
+	traceHeaders func(context.Context) http.Header
+
+	decompress bool
+
+	// shutdown is canceled by Close, canceling every in-flight request.
+	shutdown  context.Context
+	cancelAll context.CancelFunc
+}
+
+// ErrClientClosed is returned by requests made after the client was closed.
+var ErrClientClosed = errors.New("client closed")
+
+func newTransportConfig() transportConfig {
+	shutdown, cancel := context.WithCancel(context.Background())
+	return transportConfig{
+		retry:     defaultRetryPolicy(),
+		metrics:   nopMetrics{},
+		shutdown:  shutdown,
+		cancelAll: cancel,
+	}
+}
+
+// closeClient cancels in-flight requests and closes idle connections held by
+// client. The shared default client is left alone, since other code in the
+// process may be using it.
+func (t *transportConfig) closeClient(client *http.Client) {
+	if t.cancelAll != nil {
+		t.cancelAll()
+	}
+	if client != nil && client != http.DefaultClient {
+		client.CloseIdleConnections()
+	}
+}
+
+type userAgentSuffixKey struct{}
+
+// WithUserAgentSuffixCtx returns a copy of ctx that makes requests made with
+// it append suffix to the client's User-Agent, for example to attribute
+// traffic to a tenant. The client's own user agent, DefaultUserAgent unless
+// replaced with WithUserAgent, always comes first so that Customer.io can
+// still identify the library and its version.
+func WithUserAgentSuffixCtx(ctx context.Context, suffix string) context.Context {
+	return context.WithValue(ctx, userAgentSuffixKey{}, suffix)
+}
+
+// send issues the request built by newReq to an endpoint of class, applying
+// the configured timeouts, rate limits and retries, and returns the response
+// body and status code.
+func (t *transportConfig) send(ctx context.Context, client *http.Client, class EndpointClass, method, url string, requestBytes int, newReq func(ctx context.Context) (*http.Request, error)) ([]byte, int, error) {
+	start := time.Now()
+	if t.shutdown != nil {
+		if t.shutdown.Err() != nil {
+			return nil, 0, ErrClientClosed
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(t.shutdown, cancel)
+		defer stop()
+	}
+	if _, ok := ctx.Deadline(); !ok && t.maxCallDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.maxCallDuration)
+		defer cancel()
+	}
+	reqCtx, cancel := withEndpointTimeout(ctx, t.timeouts, class)
+	defer cancel()
+
+	var (
+		body   []byte
+		status int
+	)
+	resp, err := t.retry.do(reqCtx, client, func() (*http.Request, error) {
+		if err := t.limits.wait(reqCtx, class); err != nil {
+			return nil, err
+		}
+		req, err := newReq(reqCtx)
+		if err != nil {
+			return nil, err
+		}
+		if t.decompress {
+			// Setting the header ourselves stops the transport from
+			// decompressing the response, so readBody does it instead.
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		if suffix, ok := ctx.Value(userAgentSuffixKey{}).(string); ok && suffix != "" {
+			req.Header.Set("User-Agent", req.Header.Get("User-Agent")+" "+suffix)
+		}
+		if t.traceHeaders != nil {
+			for k, vs := range t.traceHeaders(ctx) {
+				req.Header.Del(k)
+				for _, v := range vs {
+					req.Header.Add(k, v)
+				}
+			}
+		}
+		return req, nil
+	}, func() {
+		if t.metrics != nil {
+			t.metrics.IncError(string(class), "retry_budget_exhausted")
+		}
+	})
+	if err == nil {
+		defer resp.Body.Close()
+		status = resp.StatusCode
+		body, err = readBody(resp)
+	}
+
+	duration := time.Since(start)
+	t.record(class, status, duration, err)
+	t.observe(ctx, RequestMetrics{
+		Method:        method,
+		URL:           url,
+		StatusCode:    status,
+		Duration:      duration,
+		RequestBytes:  requestBytes,
+		ResponseBytes: len(body),
+		Err:           err,
+	})
+	if err != nil {
+		if t.shutdown != nil && t.shutdown.Err() != nil {
+			return nil, 0, ErrClientClosed
+		}
+		return nil, 0, err
+	}
+	return body, status, nil
+}
+
+// readBody reads the response body, decompressing it if the server
+// compressed it with gzip.
+func readBody(resp *http.Response) ([]byte, error) {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return ioutil.ReadAll(resp.Body)
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"time"
 )
 
 type SendEmailRequest struct {
@@ -30,6 +31,42 @@ type SendEmailRequest struct {
 	SendToUnsubscribed      *bool                  `json:"send_to_unsubscribed,omitempty"`
 	EnableTracking          *bool                  `json:"tracked,omitempty"`
 	QueueDraft              *bool                  `json:"queue_draft,omitempty"`
+	// SendAt schedules the message to be sent later. It is sent as Unix
+	// seconds, and may not be in the past.
+	SendAt *time.Time `json:"-"`
+}
+
+// sendAtSkew is how far in the past SendAt may be, to allow for clock drift.
+const sendAtSkew = time.Minute
+
+func (e SendEmailRequest) MarshalJSON() ([]byte, error) {
+	type request SendEmailRequest
+	var sendAt int64
+	if e.SendAt != nil {
+		sendAt = e.SendAt.Unix()
+	}
+	return json.Marshal(struct {
+		request
+		SendAt int64 `json:"send_at,omitempty"`
+	}{request(e), sendAt})
+}
+
+func (e *SendEmailRequest) UnmarshalJSON(b []byte) error {
+	type request SendEmailRequest
+	var r struct {
+		request
+		SendAt json.RawMessage `json:"send_at"`
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return err
+	}
+	sendAt, err := parseCustomerIOTime(r.SendAt)
+	if err != nil {
+		return err
+	}
+	*e = SendEmailRequest(r.request)
+	e.SendAt = sendAt
+	return nil
 }
 
 var ErrAttachmentExists = errors.New("attachment with this name already exists")
@@ -61,6 +98,30 @@ type SendEmailResponse struct {
 
 // SendEmail sends a single transactional email using the Customer.io transactional API
 func (c *APIClient) SendEmail(ctx context.Context, req *SendEmailRequest) (*SendEmailResponse, error) {
+	return c.sendEmail(ctx, req)
+}
+
+// ResendEmail sends a transactional email again, for example after a
+// delivery failed for a transient reason. Customer.io can't retry a delivery
+// by id, so the original request must be supplied, and it is sent as a new
+// delivery. If the request was scheduled with a SendAt that has passed, it is
+// sent immediately. Customer.io doesn't deduplicate sends, so only resend
+// messages known not to have been delivered; like SendEmail, the request is
+// never retried automatically.
+func (c *APIClient) ResendEmail(ctx context.Context, req SendEmailRequest) (*SendEmailResponse, error) {
+	if req.SendAt != nil && req.SendAt.Before(time.Now()) {
+		req.SendAt = nil
+	}
+	return c.sendEmail(ctx, &req)
+}
+
+func (c *APIClient) sendEmail(ctx context.Context, req *SendEmailRequest) (*SendEmailResponse, error) {
+	if req == nil {
+		return nil, ParamError{Param: "req"}
+	}
+	if req.SendAt != nil && req.SendAt.Before(time.Now().Add(-sendAtSkew)) {
+		return nil, ParamError{Param: "sendAt", Reason: "in the past"}
+	}
 	body, statusCode, err := c.doRequest(ctx, "POST", "/v1/send/email", req)
 	if err != nil {
 		return nil, err
@@ -101,3 +101,113 @@ func TestSendEmailError(t *testing.T) {
 		t.Errorf("Expected TransactionalError, got: %#v", e)
 	}
 }
+
+func TestGetTransactionalVariables(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/transactional/4/contents" {
+			t.Errorf("unexpected path: %s", req.URL.Path)
+		}
+		w.Write([]byte(`{"contents":[{
+			"id": 1,
+			"subject": "Hi {{ trigger.name }}",
+			"body": "{% if trigger.order.total > 0 %}{{trigger.order.total}}{% endif %} trigger.plain {{ customer.email }} {{trigger.name}}"
+		}]}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	vars, err := api.GetTransactionalVariables(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"name", "order"}; !reflect.DeepEqual(vars, want) {
+		t.Errorf("wrong variables. got: %v, want: %v", vars, want)
+	}
+}
+
+func TestSendEmailSendAt(t *testing.T) {
+	sendAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if got := body["send_at"]; got != float64(sendAt.Unix()) {
+			t.Errorf("wrong send_at. got: %v, want: %d", got, sendAt.Unix())
+		}
+		w.Write([]byte(`{"delivery_id":"ABCDEFG","queued_at":1500111111}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+
+	req := &customerio.SendEmailRequest{
+		Identifiers:            map[string]string{"id": "customer_1"},
+		TransactionalMessageID: "1",
+		SendAt:                 &sendAt,
+	}
+	if _, err := api.SendEmail(context.Background(), req); err != nil {
+		t.Error(err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	req.SendAt = &past
+	_, err := api.SendEmail(context.Background(), req)
+	checkParamError(t, err, "sendAt")
+
+	_, err = api.SendEmail(context.Background(), nil)
+	checkParamError(t, err, "req")
+}
+
+func TestResendEmail(t *testing.T) {
+	attempts := 0
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attempts++
+		body = nil
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"delivery_id":"ABCDEFG","queued_at":1500111111}`))
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey", customerio.WithRetries(1))
+	api.URL = srv.URL
+
+	sendAt := time.Now().Add(-time.Hour)
+	req := customerio.SendEmailRequest{
+		Identifiers:            map[string]string{"id": "customer_1"},
+		TransactionalMessageID: "1",
+		SendAt:                 &sendAt,
+	}
+
+	// Sends aren't deduplicated, so a failed resend must not be retried.
+	if _, err := api.ResendEmail(context.Background(), req); err == nil {
+		t.Error("expected error for a failed resend")
+	}
+	if attempts != 1 {
+		t.Errorf("failed resend was retried. got: %d attempts, want: 1", attempts)
+	}
+
+	resp, err := api.ResendEmail(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.DeliveryID != "ABCDEFG" {
+		t.Errorf("wrong response: %#v", resp)
+	}
+	if _, ok := body["send_at"]; ok {
+		t.Errorf("past send_at was resent: %v", body["send_at"])
+	}
+	if req.SendAt != &sendAt {
+		t.Error("caller's request was modified")
+	}
+}
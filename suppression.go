@@ -0,0 +1,65 @@
+package customerio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// IsSuppressed reports whether the customer's email address is on the
+// suppression list of the workspace's email provider (ESP), for example after
+// a hard bounce or spam report, so that emails to them won't be delivered. It
+// doesn't report profile suppression with the track API's suppress action:
+// suppressed profiles are deleted, so they are reported as
+// ErrCustomerNotFound.
+//
+// The customer is read first for every identifier type, and
+// ErrCustomerNotFound is returned if no customer has the id. Their email
+// attribute is then searched for in the ESP's suppression lists; a customer
+// without an email, or whose email the ESP has no record of, isn't
+// suppressed.
+func (c *APIClient) IsSuppressed(ctx context.Context, id string, idType IdentifierType) (bool, error) {
+	if id == "" {
+		return false, ParamError{Param: "id"}
+	}
+	attrs, err := c.customerAttributes(ctx, id, idType)
+	if err != nil {
+		return false, err
+	}
+	email, _ := attrs["email"].(string)
+	if email == "" && idType == IdentifierTypeEmail {
+		email = id
+	}
+	if email == "" {
+		return false, nil
+	}
+
+	url := fmt.Sprintf("/v1/esp/search_suppression/%s", url.PathEscape(email))
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	if statusCode == http.StatusNotFound {
+		return false, nil
+	} else if statusCode != http.StatusOK {
+		return false, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var resp struct {
+		Suppressions []struct {
+			Type       string            `json:"type"`
+			Suppressed []json.RawMessage `json:"suppressed"`
+		} `json:"suppressions"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return false, err
+	}
+	for _, s := range resp.Suppressions {
+		if len(s.Suppressed) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,56 @@
+package customerio_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/customerio/go-customerio/v3"
+)
+
+func TestIsSuppressed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/customers/1/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"1","email":"bounced@example.com"}}}`))
+		case "/v1/customers/ok@example.com/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"2","email":"ok@example.com"}}}`))
+		case "/v1/customers/unknown-to-esp@example.com/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"3","email":"unknown-to-esp@example.com"}}}`))
+		case "/v1/customers/4/attributes":
+			w.Write([]byte(`{"customer":{"attributes":{"id":"4"}}}`))
+		case "/v1/esp/search_suppression/bounced@example.com":
+			w.Write([]byte(`{"suppressions":[{"type":"bounces","suppressed":[{"email":"bounced@example.com","reason":"550"}]},{"type":"spam_reports","suppressed":[]}]}`))
+		case "/v1/esp/search_suppression/ok@example.com":
+			w.Write([]byte(`{"suppressions":[{"type":"bounces","suppressed":[]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := customerio.NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	if suppressed, err := api.IsSuppressed(ctx, "1", customerio.IdentifierTypeID); err != nil || !suppressed {
+		t.Errorf("expected suppressed customer, got: %v, %v", suppressed, err)
+	}
+	if suppressed, err := api.IsSuppressed(ctx, "ok@example.com", customerio.IdentifierTypeEmail); err != nil || suppressed {
+		t.Errorf("expected unsuppressed customer, got: %v, %v", suppressed, err)
+	}
+	if suppressed, err := api.IsSuppressed(ctx, "unknown-to-esp@example.com", customerio.IdentifierTypeEmail); err != nil || suppressed {
+		t.Errorf("expected unsuppressed customer, got: %v, %v", suppressed, err)
+	}
+	if suppressed, err := api.IsSuppressed(ctx, "4", customerio.IdentifierTypeID); err != nil || suppressed {
+		t.Errorf("expected unsuppressed customer without an email, got: %v, %v", suppressed, err)
+	}
+	if _, err := api.IsSuppressed(ctx, "2", customerio.IdentifierTypeID); !errors.Is(err, customerio.ErrCustomerNotFound) {
+		t.Errorf("expected ErrCustomerNotFound, got: %v", err)
+	}
+	if _, err := api.IsSuppressed(ctx, "missing@example.com", customerio.IdentifierTypeEmail); !errors.Is(err, customerio.ErrCustomerNotFound) {
+		t.Errorf("expected ErrCustomerNotFound, got: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package customerio
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseCustomerIOTime converts a timestamp as returned by the Customer.io
+// APIs into a time.Time. Timestamps are returned as epoch seconds, either as
+// a JSON number or a string, or as RFC3339 strings. A nil time is returned
+// for empty and null values.
+func parseCustomerIOTime(v interface{}) (*time.Time, error) {
+	var t time.Time
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		t = v
+	case int:
+		t = time.Unix(int64(v), 0)
+	case int64:
+		t = time.Unix(v, 0)
+	case float64:
+		sec, frac := math.Modf(v)
+		t = time.Unix(int64(sec), int64(frac*1e9))
+	case json.Number:
+		return parseCustomerIOTime(string(v))
+	case json.RawMessage:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		var decoded interface{}
+		dec := json.NewDecoder(strings.NewReader(string(v)))
+		dec.UseNumber()
+		if err := dec.Decode(&decoded); err != nil {
+			return nil, err
+		}
+		return parseCustomerIOTime(decoded)
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			return nil, nil
+		}
+		if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+			t = time.Unix(sec, 0)
+		} else if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return parseCustomerIOTime(f)
+		} else if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", v)
+		}
+	default:
+		return nil, fmt.Errorf("invalid timestamp type %T", v)
+	}
+	return &t, nil
+}
+
+// maxEpochSeconds is the start of the year 3000. Later timestamps are almost
+// certainly milliseconds mistakenly treated as seconds.
+const maxEpochSeconds = 32503680000
+
+// epochSeconds converts t to the Unix seconds expected by the track API. If
+// t is implausibly far in the future it was most likely built from a
+// millisecond timestamp: with autoMillis the value is divided by 1000,
+// otherwise a ParamError is returned for param.
+func epochSeconds(param string, t time.Time, autoMillis bool) (int64, error) {
+	sec := t.Unix()
+	if sec <= maxEpochSeconds {
+		return sec, nil
+	}
+	if autoMillis && sec/1000 <= maxEpochSeconds {
+		return sec / 1000, nil
+	}
+	return 0, ParamError{Param: param, Reason: "timestamp is after the year 3000, it may be in milliseconds"}
+}
@@ -0,0 +1,66 @@
+package customerio
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseCustomerIOTime(t *testing.T) {
+	epoch := time.Unix(1500111111, 0)
+	rfc := time.Date(2017, 7, 15, 9, 31, 51, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		in      interface{}
+		want    *time.Time
+		wantErr bool
+	}{
+		{"nil", nil, nil, false},
+		{"empty string", "", nil, false},
+		{"int", 1500111111, &epoch, false},
+		{"int64", int64(1500111111), &epoch, false},
+		{"float64", float64(1500111111), &epoch, false},
+		{"json number", json.Number("1500111111"), &epoch, false},
+		{"numeric string", "1500111111", &epoch, false},
+		{"rfc3339 string", "2017-07-15T09:31:51Z", &rfc, false},
+		{"raw number", json.RawMessage(`1500111111`), &epoch, false},
+		{"raw string", json.RawMessage(`"1500111111"`), &epoch, false},
+		{"raw null", json.RawMessage(`null`), nil, false},
+		{"invalid string", "yesterday", nil, true},
+		{"invalid type", true, nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseCustomerIOTime(c.in)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("unexpected error state. got: %v, wantErr: %v", err, c.wantErr)
+			}
+			if (got == nil) != (c.want == nil) {
+				t.Fatalf("wrong time. got: %v, want: %v", got, c.want)
+			}
+			if got != nil && !got.Equal(*c.want) {
+				t.Errorf("wrong time. got: %v, want: %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestEpochSeconds(t *testing.T) {
+	seconds := time.Unix(1500111111, 0)
+	millis := time.Unix(1500111111000, 0)
+
+	if got, err := epochSeconds("created_at", seconds, false); err != nil || got != 1500111111 {
+		t.Errorf("wrong seconds. got: %d, %v", got, err)
+	}
+
+	_, err := epochSeconds("created_at", millis, false)
+	if pe, ok := err.(ParamError); !ok || pe.Param != "created_at" {
+		t.Errorf("expected ParamError for milliseconds, got: %v", err)
+	}
+
+	if got, err := epochSeconds("created_at", millis, true); err != nil || got != 1500111111 {
+		t.Errorf("wrong converted seconds. got: %d, %v", got, err)
+	}
+}
@@ -0,0 +1,47 @@
+package customerio
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// EndpointClass groups endpoints with similar latency expectations, for use
+// with WithEndpointTimeout.
+type EndpointClass string
+
+const (
+	EndpointClassTrack         EndpointClass = "track"
+	EndpointClassApp           EndpointClass = "app"
+	EndpointClassBatch         EndpointClass = "batch"
+	EndpointClassTransactional EndpointClass = "transactional"
+	EndpointClassExport        EndpointClass = "export"
+)
+
+// trackEndpointClass returns the class of a track API request URL.
+func trackEndpointClass(url string) EndpointClass {
+	if strings.Contains(url, "/api/v2/batch") {
+		return EndpointClassBatch
+	}
+	return EndpointClassTrack
+}
+
+// appEndpointClass returns the class of an App API request path.
+func appEndpointClass(path string) EndpointClass {
+	switch {
+	case strings.HasPrefix(path, "/v1/send/"):
+		return EndpointClassTransactional
+	case strings.HasPrefix(path, "/v1/exports"):
+		return EndpointClassExport
+	}
+	return EndpointClassApp
+}
+
+// withEndpointTimeout bounds ctx by the timeout configured for class, if any.
+// A deadline already on ctx still applies if it is sooner.
+func withEndpointTimeout(ctx context.Context, timeouts map[EndpointClass]time.Duration, class EndpointClass) (context.Context, context.CancelFunc) {
+	if d, ok := timeouts[class]; ok && d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
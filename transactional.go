@@ -1,7 +1,12 @@
 package customerio
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
 	"time"
 )
 
@@ -15,14 +20,20 @@ type TransactionalResponse struct {
 
 func (t *TransactionalResponse) UnmarshalJSON(b []byte) error {
 	var r struct {
-		DeliveryID string `json:"delivery_id"`
-		QueuedAt   int64  `json:"queued_at"`
+		DeliveryID string          `json:"delivery_id"`
+		QueuedAt   json.RawMessage `json:"queued_at"`
 	}
 	if err := json.Unmarshal(b, &r); err != nil {
 		return err
 	}
+	queuedAt, err := parseCustomerIOTime(r.QueuedAt)
+	if err != nil {
+		return err
+	}
 	t.DeliveryID = r.DeliveryID
-	t.QueuedAt = time.Unix(r.QueuedAt, 0)
+	if queuedAt != nil {
+		t.QueuedAt = *queuedAt
+	}
 	return nil
 }
 
@@ -37,3 +48,57 @@ type TransactionalError struct {
 func (e *TransactionalError) Error() string {
 	return e.Err
 }
+
+var (
+	liquidTagPattern  = regexp.MustCompile(`(?s){{.*?}}|{%.*?%}`)
+	triggerVarPattern = regexp.MustCompile(`\btrigger\.([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// GetTransactionalVariables returns the names of the message_data variables
+// referenced by a transactional message, sorted and without duplicates. The
+// API does not list a template's variables, so they are parsed from the
+// Liquid in the message's contents: every trigger.<name> inside a {{ }} or
+// {% %} tag. Only the top-level name is returned for nested values such as
+// trigger.order.total, and variables referenced indirectly, for example
+// through a Liquid assign or a snippet, are not found.
+func (c *APIClient) GetTransactionalVariables(ctx context.Context, id int) ([]string, error) {
+	if id <= 0 {
+		return nil, ParamError{Param: "transactionalMessageID"}
+	}
+	url := fmt.Sprintf("/v1/transactional/%d/contents", id)
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var resp struct {
+		Contents []map[string]interface{} `json:"contents"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, content := range resp.Contents {
+		for _, field := range content {
+			text, ok := field.(string)
+			if !ok {
+				continue
+			}
+			for _, tag := range liquidTagPattern.FindAllString(text, -1) {
+				for _, m := range triggerVarPattern.FindAllStringSubmatch(tag, -1) {
+					if !seen[m[1]] {
+						seen[m[1]] = true
+						names = append(names, m[1])
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,194 @@
+package customerio
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes a single field rejected by the API.
+type FieldError struct {
+	// Field is the name of the offending field, if the API reported one.
+	Field string
+	// Reason is the API's description of the problem.
+	Reason string
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationError is returned when the API rejects a write with a 400
+// response describing the fields that failed validation, in which case it
+// wraps the underlying *CustomerIOError. With WithAttributeValidation, it is
+// also returned without a request for numbers JSON can't encode.
+type ValidationError struct {
+	Fields []FieldError
+
+	err *CustomerIOError
+}
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.String()
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(msgs, "; "))
+}
+
+func (e ValidationError) Unwrap() error {
+	if e.err == nil {
+		return nil
+	}
+	return e.err
+}
+
+// validationError parses the body of a failed response into a
+// ValidationError. It returns the original error if the response is not a
+// 400 or its body does not describe any fields.
+func validationError(err *CustomerIOError) error {
+	if err.status != http.StatusBadRequest {
+		return err
+	}
+
+	var body struct {
+		Errors []struct {
+			Field   string `json:"field"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Meta struct {
+			Error  string   `json:"error"`
+			Errors []string `json:"errors"`
+		} `json:"meta"`
+	}
+	if json.Unmarshal(err.body, &body) != nil {
+		return err
+	}
+
+	var fields []FieldError
+	for _, e := range body.Errors {
+		reason := e.Reason
+		if e.Message != "" {
+			reason = e.Message
+		}
+		fields = append(fields, FieldError{Field: e.Field, Reason: reason})
+	}
+	for _, e := range body.Meta.Errors {
+		fields = append(fields, FieldError{Reason: e})
+	}
+	if body.Meta.Error != "" {
+		fields = append(fields, FieldError{Reason: body.Meta.Error})
+	}
+	if len(fields) == 0 {
+		return err
+	}
+	return ValidationError{Fields: fields, err: err}
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// validateAttributes checks that every value in attrs can be encoded as
+// JSON, returning a ParamError naming the path to the first value that can't,
+// such as "attributes.address.geo", along with its Go type. NaN and infinite
+// floats are reported with a ValidationError for their path instead. Struct
+// fields are named in paths as they are in the encoded JSON.
+func validateAttributes(param string, attrs map[string]interface{}) error {
+	return validateValue(param, reflect.ValueOf(attrs), map[uintptr]bool{})
+}
+
+// validateValue checks v, reporting problems at path. visiting holds the
+// maps, slices and pointers enclosing v, to detect cycles.
+func validateValue(path string, v reflect.Value, visiting map[uintptr]bool) error {
+	if !v.IsValid() {
+		return nil
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return ParamError{Param: path, Reason: "unsupported type " + t.String()}
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return ValidationError{Fields: []FieldError{{Field: path, Reason: "unsupported value " + strconv.FormatFloat(f, 'g', -1, 64)}}}
+		}
+	case reflect.Interface:
+		return validateValue(path, v.Elem(), visiting)
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() != reflect.Slice || v.Len() > 0 {
+			ptr := v.Pointer()
+			if visiting[ptr] {
+				return ParamError{Param: path, Reason: "cyclic value of type " + t.String()}
+			}
+			visiting[ptr] = true
+			defer delete(visiting, ptr)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return validateValue(path, v.Elem(), visiting)
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return ParamError{Param: path, Reason: "unsupported map key type " + t.Key().String()}
+			}
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if err := validateValue(path+"."+key, iter.Value(), visiting); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings.
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(path+"["+strconv.Itoa(i)+"]", v.Index(i), visiting); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			fieldPath := path + "." + f.Name
+			if name, _, _ := strings.Cut(tag, ","); name != "" {
+				fieldPath = path + "." + name
+			} else if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				// Untagged embedded structs have their fields promoted.
+				fieldPath = path
+			}
+			if err := validateValue(fieldPath, v.Field(i), visiting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
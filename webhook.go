@@ -0,0 +1,130 @@
+package customerio
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrWebhookSignature is returned by VerifyWebhook when a payload's
+	// signature is missing or does not match.
+	ErrWebhookSignature = errors.New("invalid webhook signature")
+	// ErrWebhookExpired is returned by VerifyWebhook when a payload's
+	// signature is valid but its timestamp is outside the tolerance, which
+	// usually means it is being replayed.
+	ErrWebhookExpired = errors.New("webhook timestamp outside tolerance")
+)
+
+const (
+	// DefaultWebhookTolerance is the tolerance used by VerifyWebhook when none
+	// is given.
+	DefaultWebhookTolerance = 5 * time.Minute
+	// webhookClockSkew is how far in the future a timestamp may be, to allow
+	// for clock drift between Customer.io and the receiver.
+	webhookClockSkew = time.Minute
+)
+
+// VerifyWebhook checks that a reporting webhook payload was signed by
+// Customer.io with signingKey, using the X-CIO-Timestamp and X-CIO-Signature
+// headers of the request. Payloads signed more than tolerance ago are
+// rejected with ErrWebhookExpired so that captured payloads can't be
+// replayed; a tolerance of zero or less uses DefaultWebhookTolerance.
+func VerifyWebhook(signingKey string, header http.Header, body []byte, tolerance time.Duration) error {
+	return verifyWebhook(signingKey, header, body, tolerance, time.Now())
+}
+
+func verifyWebhook(signingKey string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp := header.Get("X-CIO-Timestamp")
+	signature, err := hex.DecodeString(header.Get("X-CIO-Signature"))
+	if timestamp == "" || err != nil || len(signature) == 0 {
+		return ErrWebhookSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return ErrWebhookSignature
+	}
+
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookSignature
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	signedAt := time.Unix(sec, 0)
+	if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(webhookClockSkew)) {
+		return ErrWebhookExpired
+	}
+	return nil
+}
+
+// Webhook is a reporting webhook, which delivers message and customer events
+// from Customer.io to an endpoint. Payloads are signed with the workspace's
+// webhook signing key and can be checked with VerifyWebhook.
+type Webhook struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Disabled bool   `json:"disabled"`
+	// FullResolution sends every event rather than only the first of each
+	// type for a message.
+	FullResolution bool `json:"full_resolution"`
+	// WithContent includes message content in the payloads.
+	WithContent bool `json:"with_content"`
+	// Events are the event types delivered, such as "email_sent".
+	Events []string `json:"events"`
+}
+
+// ListWebhooks returns the workspace's reporting webhooks.
+func (c *APIClient) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	body, statusCode, err := c.doRequest(ctx, "GET", "/v1/reporting_webhooks", nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, &CustomerIOError{status: statusCode, url: "/v1/reporting_webhooks", body: body}
+	}
+
+	var envelope struct {
+		Webhooks []Webhook `json:"reporting_webhooks"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	return envelope.Webhooks, nil
+}
+
+// GetWebhook returns a reporting webhook, for example to check that it is
+// configured and enabled for the expected endpoint and events. The API has no
+// way to send a test event to a webhook; use the "Send test" action in the
+// Customer.io UI to check that the endpoint is reachable.
+func (c *APIClient) GetWebhook(ctx context.Context, webhookID int) (Webhook, error) {
+	if webhookID <= 0 {
+		return Webhook{}, ParamError{Param: "webhookID"}
+	}
+	url := fmt.Sprintf("/v1/reporting_webhooks/%d", webhookID)
+	body, statusCode, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if statusCode != http.StatusOK {
+		return Webhook{}, &CustomerIOError{status: statusCode, url: url, body: body}
+	}
+
+	var webhook Webhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
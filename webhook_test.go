@@ -0,0 +1,94 @@
+package customerio
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	const key = "signing-key"
+	body := []byte(`{"event_id":"01E2EMRMM6TZ12TF9WGZN0WJQT","metric":"sent"}`)
+	now := time.Unix(1600000000, 0)
+
+	sign := func(ts time.Time, key string) http.Header {
+		timestamp := strconv.FormatInt(ts.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte("v0:" + timestamp + ":"))
+		mac.Write(body)
+		h := http.Header{}
+		h.Set("X-CIO-Timestamp", timestamp)
+		h.Set("X-CIO-Signature", hex.EncodeToString(mac.Sum(nil)))
+		return h
+	}
+
+	cases := []struct {
+		name      string
+		header    http.Header
+		tolerance time.Duration
+		want      error
+	}{
+		{"valid", sign(now.Add(-time.Minute), key), 0, nil},
+		{"missing", http.Header{}, 0, ErrWebhookSignature},
+		{"wrong key", sign(now, "other"), 0, ErrWebhookSignature},
+		{"replayed", sign(now.Add(-10*time.Minute), key), 0, ErrWebhookExpired},
+		{"custom tolerance", sign(now.Add(-10*time.Minute), key), time.Hour, nil},
+		{"future", sign(now.Add(10*time.Minute), key), 0, ErrWebhookExpired},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := verifyWebhook(key, c.header, body, c.tolerance, now); err != c.want {
+				t.Errorf("got: %v, want: %v", err, c.want)
+			}
+		})
+	}
+}
+
+func TestListWebhooks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/reporting_webhooks":
+			w.Write([]byte(`{"reporting_webhooks":[{"id":3,"name":"events","endpoint":"https://example.com/hook","disabled":false,"full_resolution":true,"with_content":false,"events":["email_sent","email_opened"]}]}`))
+		case "/v1/reporting_webhooks/3":
+			w.Write([]byte(`{"id":3,"name":"events"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := NewAPIClient("myKey")
+	api.URL = srv.URL
+	ctx := context.Background()
+
+	webhooks, err := api.ListWebhooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 1 || webhooks[0].ID != 3 || webhooks[0].Endpoint != "https://example.com/hook" ||
+		!webhooks[0].FullResolution || len(webhooks[0].Events) != 2 {
+		t.Errorf("wrong webhooks: %#v", webhooks)
+	}
+
+	webhook, err := api.GetWebhook(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhook.ID != 3 || webhook.Name != "events" {
+		t.Errorf("wrong webhook: %#v", webhook)
+	}
+	var cioErr *CustomerIOError
+	if _, err := api.GetWebhook(ctx, 4); !errors.As(err, &cioErr) || cioErr.status != http.StatusNotFound {
+		t.Errorf("expected not found error, got: %v", err)
+	}
+	if _, err := api.GetWebhook(ctx, 0); !errors.As(err, new(ParamError)) {
+		t.Errorf("expected a ParamError, got: %v", err)
+	}
+}